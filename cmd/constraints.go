@@ -0,0 +1,162 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const updateAllowPatch = "patch"
+const updateAllowMinor = "minor"
+const updateAllowMajor = "major"
+
+// GetAllowedUpdate returns the kind of version changes allowed for packageName by the first
+// matching entry of updateConstraints, or 'major' if there's no such entry.
+func GetAllowedUpdate(packageName string, updateConstraints []UpdateConstraint) string {
+	for _, c := range updateConstraints {
+		match, err := regexp.MatchString(GetPackageRegex(c.Package), packageName)
+		checkError(err)
+		if !match {
+			continue
+		}
+		if c.Allow != updateAllowPatch && c.Allow != updateAllowMinor && c.Allow != updateAllowMajor {
+			log.Fatal("Unknown value '", c.Allow, "' of updateConstraints entry for ", c.Package,
+				". Please use one of: ", updateAllowPatch, ", ", updateAllowMinor, ", ", updateAllowMajor, ".")
+		}
+		return c.Allow
+	}
+	return updateAllowMajor
+}
+
+// CheckIfUpdateAllowed checks if the change from oldVersion to newVersion is allowed by allowedUpdate:
+// for 'patch', the first two version components must not change, and for 'minor', the first one.
+func CheckIfUpdateAllowed(oldVersion string, newVersion string, allowedUpdate string) bool {
+	var fixedComponents int
+	switch allowedUpdate {
+	case updateAllowPatch:
+		fixedComponents = 2
+	case updateAllowMinor:
+		fixedComponents = 1
+	default:
+		return true
+	}
+	oldComponents := strings.FieldsFunc(oldVersion, splitVersion)
+	newComponents := strings.FieldsFunc(newVersion, splitVersion)
+	for i := 0; i < fixedComponents; i++ {
+		// Missing version components are treated as 0, e.g. '1' is the same as '1.0'.
+		oldComponent, newComponent := "0", "0"
+		if i < len(oldComponents) {
+			oldComponent = oldComponents[i]
+		}
+		if i < len(newComponents) {
+			newComponent = newComponents[i]
+		}
+		if stringsToInts([]string{oldComponent})[0] != stringsToInts([]string{newComponent})[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetArchivedPackageVersions downloads the index of the Archive directory for packageName in the
+// CRAN-like repository located at repositoryURL, and returns the archived versions sorted from the newest.
+func GetArchivedPackageVersions(repositoryURL string, packageName string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) []string {
+	archiveURL := repositoryURL + "/src/contrib/Archive/" + packageName + "/"
+	log.Debug("Downloading ", archiveURL)
+	_, archiveIndex, err := downloadFileFunction(archiveURL, map[string]string{})
+	if err != nil {
+		log.Warn("An error occurred while downloading ", archiveURL)
+		return []string{}
+	}
+	re := regexp.MustCompile(regexp.QuoteMeta(packageName) + `_([0-9][0-9.\-]*)\.tar\.gz`)
+	versions := []string{}
+	for _, match := range re.FindAllStringSubmatch(archiveIndex, -1) {
+		if !stringInSlice(match[1], versions) {
+			versions = append(versions, match[1])
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CheckIfVersionSufficient(versions[i], ">", versions[j])
+	})
+	return versions
+}
+
+// GetArchivedPackageFields downloads the archived source package packageName in the given version
+// from the CRAN-like repository located at repositoryURL, and returns the fields of its DESCRIPTION file.
+func GetArchivedPackageFields(repositoryURL string, packageName string, version string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) (map[string]string, error) {
+	tarballURL := repositoryURL + "/src/contrib/Archive/" + packageName + "/" +
+		packageName + "_" + version + ".tar.gz"
+	log.Debug("Downloading ", tarballURL)
+	_, tarballContent, err := downloadFileFunction(tarballURL, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+	gzipReader, err := gzip.NewReader(strings.NewReader(tarballContent))
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == packageName+"/DESCRIPTION" {
+			description, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, err
+			}
+			return ParseDCF(string(description)), nil
+		}
+	}
+	return nil, errors.New("DESCRIPTION file not found in " + tarballURL)
+}
+
+// GetAllowedArchivedPackage searches the Archive of the CRAN-like repository located at repositoryURL
+// for the newest version of packageName higher than oldVersion, to which the update is allowed by
+// allowedUpdate. It returns that version and the fields of its DESCRIPTION file, or an empty version
+// if no such version could be found.
+func GetAllowedArchivedPackage(repositoryURL string, packageName string, oldVersion string, allowedUpdate string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) (string, map[string]string) {
+	for _, version := range GetArchivedPackageVersions(repositoryURL, packageName, downloadFileFunction) {
+		if !CheckIfVersionSufficient(version, ">", oldVersion) {
+			break
+		}
+		if !CheckIfUpdateAllowed(oldVersion, version, allowedUpdate) {
+			continue
+		}
+		fields, err := GetArchivedPackageFields(repositoryURL, packageName, version, downloadFileFunction)
+		if err != nil {
+			log.Warn("Could not read package ", packageName, " version ", version, " from Archive: ", err)
+			continue
+		}
+		return version, fields
+	}
+	return "", nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getPackageTarball returns the contents of a source package tarball containing only the DESCRIPTION file.
+func getPackageTarball(packageName string, description string) string {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name: packageName + "/DESCRIPTION", Mode: 0644, Size: int64(len(description)),
+	})
+	checkError(err)
+	_, err = tarWriter.Write([]byte(description))
+	checkError(err)
+	checkError(tarWriter.Close())
+	checkError(gzipWriter.Close())
+	return buffer.String()
+}
+
+func mockedDownloadArchiveFile(url string, _ map[string]string) (int64, string, error) {
+	switch url {
+	case "https://repo1.example.com/src/contrib/Archive/dplyr/":
+		return 0, `<html><body>
+<a href="dplyr_1.0.9.tar.gz">dplyr_1.0.9.tar.gz</a>
+<a href="dplyr_1.1.3.tar.gz">dplyr_1.1.3.tar.gz</a>
+<a href="dplyr_1.1.4.tar.gz">dplyr_1.1.4.tar.gz</a>
+<a href="dplyr_1.0.10.tar.gz">dplyr_1.0.10.tar.gz</a>
+</body></html>`, nil
+	case "https://repo1.example.com/src/contrib/Archive/dplyr/dplyr_1.1.4.tar.gz":
+		return 0, getPackageTarball("dplyr", "Package: dplyr\nVersion: 1.1.4\nImports: tibble (>= 3.2.0),\n  vctrs\n"), nil
+	}
+	return 0, "", errors.New("Received status code 404")
+}
+
+func Test_GetAllowedUpdate(t *testing.T) {
+	updateConstraints := []UpdateConstraint{{"dplyr", "minor"}, {"tidy*,ggplot2", "patch"}}
+	assert.Equal(t, GetAllowedUpdate("dplyr", updateConstraints), updateAllowMinor)
+	assert.Equal(t, GetAllowedUpdate("tidyr", updateConstraints), updateAllowPatch)
+	assert.Equal(t, GetAllowedUpdate("ggplot2", updateConstraints), updateAllowPatch)
+	assert.Equal(t, GetAllowedUpdate("shiny", updateConstraints), updateAllowMajor)
+}
+
+func Test_CheckIfUpdateAllowed(t *testing.T) {
+	assert.True(t, CheckIfUpdateAllowed("1.1.2", "1.1.4", updateAllowPatch))
+	assert.False(t, CheckIfUpdateAllowed("1.1.2", "1.2.0", updateAllowPatch))
+	assert.True(t, CheckIfUpdateAllowed("1.1.2", "1.2.0", updateAllowMinor))
+	assert.True(t, CheckIfUpdateAllowed("1.1", "1.1-3", updateAllowPatch))
+	assert.False(t, CheckIfUpdateAllowed("1.1.2", "2.0.0", updateAllowMinor))
+	assert.True(t, CheckIfUpdateAllowed("1.1.2", "2.0.0", updateAllowMajor))
+}
+
+func Test_GetArchivedPackageVersions(t *testing.T) {
+	assert.Equal(t,
+		GetArchivedPackageVersions("https://repo1.example.com", "dplyr", mockedDownloadArchiveFile),
+		[]string{"1.1.4", "1.1.3", "1.0.10", "1.0.9"},
+	)
+	assert.Equal(t,
+		GetArchivedPackageVersions("https://repo1.example.com", "tidyr", mockedDownloadArchiveFile),
+		[]string{},
+	)
+}
+
+func Test_GetAllowedArchivedPackage(t *testing.T) {
+	version, fields := GetAllowedArchivedPackage(
+		"https://repo1.example.com", "dplyr", "1.0.10", updateAllowMinor, mockedDownloadArchiveFile,
+	)
+	assert.Equal(t, version, "1.1.4")
+	assert.Equal(t, fields, map[string]string{
+		"Package": "dplyr", "Version": "1.1.4", "Imports": "tibble (>= 3.2.0),\nvctrs",
+	})
+	version, _ = GetAllowedArchivedPackage(
+		"https://repo1.example.com", "dplyr", "1.0.9", updateAllowPatch, mockedDownloadArchiveFile,
+	)
+	// Version 1.0.10 is allowed, but its tarball can't be downloaded.
+	assert.Equal(t, version, "")
+}
+
+func Test_GetAllowedPackageUpdate(t *testing.T) {
+	updateConstraints := []UpdateConstraint{{"dplyr", updateAllowMinor}}
+	newPackage := PackageDescription{
+		"dplyr", "2.0.0", "", "", []Dependency{}, "", "", "", "", "", "", "", []string{}, "", nil,
+	}
+	p := GetAllowedPackageUpdate(
+		"1.0.10", newPackage, "https://repo1.example.com", updateConstraints, mockedDownloadArchiveFile,
+	)
+	assert.Equal(t, p.Version, "1.1.4")
+	assert.Equal(t, p.Dependencies, []Dependency{{imports, "tibble", ">=", "3.2.0"}, {imports, "vctrs", "", ""}})
+	p = GetAllowedPackageUpdate(
+		"1.0.10", newPackage, "https://repo1.example.com", []UpdateConstraint{}, mockedDownloadArchiveFile,
+	)
+	assert.Equal(t, p, newPackage)
+	// No version allowed by the constraint can be retrieved from the Archive.
+	p = GetAllowedPackageUpdate(
+		"1.0.9", newPackage, "https://repo1.example.com", []UpdateConstraint{{"dplyr", updateAllowPatch}},
+		mockedDownloadArchiveFile,
+	)
+	assert.Equal(t, p.Version, "1.0.9")
+}
+
+func Test_UpdateRepositoryPackagesWithConstraints(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{{"Repo1", "https://repo1.example.com"}}}, nil,
+		map[string]PackageDescription{
+			"dplyr": {
+				"dplyr", "1.1.2", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"tidyr": {
+				"tidyr", "1.2.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"shiny": {
+				"shiny", "1.7.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	}
+	packagesFiles := map[string]PackagesFile{
+		"Repo1": {
+			[]PackageDescription{
+				{
+					"dplyr", "2.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"tidyr", "1.3.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"shiny", "2.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	updatedPackages := UpdateRepositoryPackages(
+		&renvLock, ".*", packagesFiles, []UpdateConstraint{{"dplyr", "minor"}, {"tidyr", "patch"}},
+		mockedDownloadArchiveFile,
+	)
+	assert.Equal(t, renvLock.Packages["dplyr"].Version, "1.1.4")
+	assert.Equal(t, renvLock.Packages["dplyr"].Requirements, []string{"tibble", "vctrs"})
+	assert.Equal(t, renvLock.Packages["tidyr"].Version, "1.2.0")
+	assert.Equal(t, renvLock.Packages["shiny"].Version, "2.0.0")
+	assert.Contains(t, updatedPackages, "dplyr")
+	assert.NotContains(t, updatedPackages, "tidyr")
+}
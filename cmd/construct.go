@@ -17,6 +17,8 @@ limitations under the License.
 package cmd
 
 import (
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -26,20 +28,33 @@ const imports = "Imports"
 const suggests = "Suggests"
 const linkingTo = "LinkingTo"
 
+// Modes of processing R recommended packages.
+const recommendedPackagesFromRepository = "repository"
+const recommendedPackagesInstalled = "installed"
+const recommendedPackagesFromPath = "path"
+
 // ConstructOutputPackageList generates a list of all packages and their dependencies
 // which should be included in the output renv.lock file,
 // based on the list of package descriptions, and information contained in the PACKAGES files.
+// Dependencies matching the comma-separated wildcard expression excludedPackages are not resolved at all.
+// installedPackages is a map from package name to package version, containing packages assumed
+// to be already installed in the target R installation, in addition to base R packages.
 func ConstructOutputPackageList(packages []PackageDescription, packagesFiles map[string]PackagesFile,
-	repositoryList []string, allowedMissingDependencyTypes []string) []PackageDescription {
+	repositoryList []string, allowedMissingDependencyTypes []string,
+	packageOverrides map[string]PackageOverride, excludedPackages string,
+	installedPackages map[string]string) []PackageDescription {
 	var outputPackageList []PackageDescription
 	fatalMissingPackageVersions := make(map[string]DependencyVersion)
 	nonFatalMissingPackageVersions := make(map[string]DependencyVersion)
+	// Map from excluded package name to the list of packages which depend on it.
+	excludedPackageDependants := make(map[string][]string)
+	excludedPackagesRegex := GetExcludedPackagesRegex(excludedPackages)
 	// Add all input packages to output list, as the packages should be downloaded from git repositories.
 	for _, p := range packages {
 		outputPackageList = append(outputPackageList, PackageDescription{
 			p.Package, p.Version, p.Source, "", []Dependency{},
 			p.RemoteType, p.RemoteHost, p.RemoteUsername, p.RemoteRepo, p.RemoteSubdir,
-			p.RemoteRef, p.RemoteSha, []string{}, "",
+			p.RemoteRef, p.RemoteSha, GetRequirements(p.Dependencies), "", p.Fields,
 		})
 	}
 	for _, p := range packages {
@@ -47,18 +62,21 @@ func ConstructOutputPackageList(packages []PackageDescription, packagesFiles map
 			if d.DependencyType == depends || d.DependencyType == imports ||
 				d.DependencyType == suggests || d.DependencyType == linkingTo {
 				if !CheckIfSkipDependency("", p.Package, d.DependencyName,
-					d.VersionOperator, d.VersionValue, &outputPackageList, p.Package) {
+					d.VersionOperator, d.VersionValue, &outputPackageList, packageOverrides,
+					excludedPackagesRegex, excludedPackageDependants, installedPackages, p.Package) {
 					log.Info(p.Package, " → ", d.DependencyName, " (", d.DependencyType, ")")
 					ResolveDependenciesRecursively(
 						&outputPackageList, d.DependencyName, d.VersionOperator,
 						d.VersionValue, d.DependencyType, allowedMissingDependencyTypes,
-						repositoryList, packagesFiles, 1, fatalMissingPackageVersions,
+						repositoryList, packagesFiles, packageOverrides, excludedPackagesRegex,
+						excludedPackageDependants, installedPackages, 1, fatalMissingPackageVersions,
 						nonFatalMissingPackageVersions, p.Package,
 					)
 				}
 			}
 		}
 	}
+	ReportExcludedPackages(excludedPackageDependants)
 	errorsString := "Packages not found in any repository:\n"
 	for packageName, versionConstraint := range nonFatalMissingPackageVersions {
 		var versionConstraintString string
@@ -88,69 +106,107 @@ func ConstructOutputPackageList(packages []PackageDescription, packagesFiles map
 	return outputPackageList
 }
 
+// GetExcludedPackagesRegex converts the comma-separated wildcard expression excludedPackages into a regexp,
+// or returns an empty string if no packages should be excluded.
+func GetExcludedPackagesRegex(excludedPackages string) string {
+	if excludedPackages == "" {
+		return ""
+	}
+	return GetPackageRegex(excludedPackages)
+}
+
 // ResolveDependenciesRecursively checks dependencies of the package, and their required versions.
 // Checks if the required version is already included in the output package list
 // (later used to generate the renv.lock), or if the dependency should be downloaded from a package repository.
 // Repeats the process recursively for all dependencies not yet processed.
+// If the package has been overridden by the user, the override takes precedence over the
+// required version and the order of repositories.
 func ResolveDependenciesRecursively(outputList *[]PackageDescription, name string, versionOperator string,
 	versionValue string, dependencyType string, allowedMissingDependencyTypes []string,
-	repositoryList []string, packagesFiles map[string]PackagesFile, recursionLevel int,
+	repositoryList []string, packagesFiles map[string]PackagesFile,
+	packageOverrides map[string]PackageOverride, excludedPackagesRegex string,
+	excludedPackageDependants map[string][]string, installedPackages map[string]string, recursionLevel int,
 	fatalMissingPackageVersions map[string]DependencyVersion,
 	nonFatalMissingPackageVersions map[string]DependencyVersion,
 	dependencyChain string) {
-	var indentation string
-	for i := 0; i < recursionLevel; i++ {
-		indentation += "  "
-	}
+	indentation := strings.Repeat("  ", recursionLevel)
 	dependencyChain += " → " + name
-	for _, r := range repositoryList {
-		// Check if the package is present in the PACKAGES file for the repository.
-		for _, p := range packagesFiles[r].Packages {
-			if p.Package == name {
-				if r != repositoryList[0] {
-					log.Warn(indentation, name, " not found in top repository. [", dependencyChain, "]")
-				}
-				// Check if package in the repository is available in sufficient version.
-				if !CheckIfVersionSufficient(p.Version, versionOperator, versionValue) {
-					log.Warn(
-						indentation, p.Package, " in repository ", r,
-						" is available in version ", p.Version,
-						" which is insufficient according to requirement ",
-						versionOperator, " ", versionValue, ". [", dependencyChain, "]",
+	// Adds the package to the output list and processes its dependencies.
+	addPackage := func(p PackageDescription, repository string, packageVersion string) {
+		// Repository is saved as an URL, and will be changed into an alias
+		// during the processing of output package list into renv.lock file.
+		*outputList = append(*outputList, PackageDescription{
+			p.Package, packageVersion, p.Source, repository, []Dependency{},
+			p.RemoteType, p.RemoteHost, p.RemoteUsername, p.RemoteRepo, p.RemoteSubdir,
+			p.RemoteRef, p.RemoteSha, GetRequirements(p.Dependencies), "", p.Fields,
+		})
+		for _, d := range p.Dependencies {
+			if d.DependencyType == depends || d.DependencyType == imports ||
+				d.DependencyType == linkingTo {
+				if !CheckIfSkipDependency(indentation, p.Package, d.DependencyName,
+					d.VersionOperator, d.VersionValue, outputList, packageOverrides,
+					excludedPackagesRegex, excludedPackageDependants, installedPackages, dependencyChain) {
+					log.Info(
+						indentation, p.Package, " → ", d.DependencyName,
+						" (", d.DependencyType, ")",
+					)
+					ResolveDependenciesRecursively(
+						outputList, d.DependencyName, d.VersionOperator, d.VersionValue,
+						d.DependencyType, allowedMissingDependencyTypes, repositoryList,
+						packagesFiles, packageOverrides, excludedPackagesRegex, excludedPackageDependants,
+						installedPackages, recursionLevel+1, fatalMissingPackageVersions,
+						nonFatalMissingPackageVersions, dependencyChain,
 					)
-					// Try to retrieve the package from the next repository.
-					continue
-				}
-				// Add package to the output list.
-				// Repository is saved as an URL, and will be changed into an alias
-				// during the processing of output package list into renv.lock file.
-				*outputList = append(*outputList, PackageDescription{
-					p.Package, p.Version, "Repository", r, []Dependency{},
-					"", "", "", "", "", "", "", []string{}, "",
-				})
-				for _, d := range p.Dependencies {
-					if d.DependencyType == depends || d.DependencyType == imports ||
-						d.DependencyType == linkingTo {
-						if !CheckIfSkipDependency(indentation, p.Package, d.DependencyName,
-							d.VersionOperator, d.VersionValue, outputList, dependencyChain) {
-							log.Info(
-								indentation, p.Package, " → ", d.DependencyName,
-								" (", d.DependencyType, ")",
-							)
-							ResolveDependenciesRecursively(
-								outputList, d.DependencyName, d.VersionOperator, d.VersionValue,
-								d.DependencyType, allowedMissingDependencyTypes, repositoryList,
-								packagesFiles, recursionLevel+1, fatalMissingPackageVersions,
-								nonFatalMissingPackageVersions, dependencyChain,
-							)
-						}
-					}
 				}
-				// Package found in repository and all dependencies processed.
-				return
 			}
 		}
 	}
+	override, overridden := packageOverrides[name]
+	if overridden && override.GitPackage.Package != "" {
+		log.Info(indentation, name, " will be downloaded from ", override.Remote, " according to the override.")
+		addPackage(override.GitPackage, "", override.GitPackage.Version)
+		return
+	}
+	searchedRepositories := repositoryList
+	if override.RepositoryURL != "" {
+		searchedRepositories = []string{override.RepositoryURL}
+	}
+	for _, r := range searchedRepositories {
+		// Check if the package is present in the PACKAGES file for the repository.
+		for _, p := range packagesFiles[r].Packages {
+			if p.Package != name {
+				continue
+			}
+			if !CheckIfRepositoryPackageAccepted(indentation, p, r, repositoryList, versionOperator,
+				versionValue, packageOverrides, dependencyChain) {
+				// Try to retrieve the package from the next repository.
+				continue
+			}
+			p.Source = "Repository"
+			addPackage(p, r, p.Version)
+			// Package found in repository and all dependencies processed.
+			return
+		}
+	}
+	pinnedPackage, pinnedPackageRepository := GetArchivedOverridePackage(override, packagesFiles)
+	if pinnedPackageRepository != "" {
+		exactVersion := GetExactVersion(override.VersionConstraints)
+		// The exact version pinned by the override is lower than the one listed in the PACKAGES file,
+		// but renv will still be able to download it from the repository archive.
+		// Since the dependencies of the archived version are unknown, the dependencies of the
+		// version currently available in the repository are used.
+		log.Warn(
+			indentation, name, " will be locked in version ", exactVersion,
+			" which should be downloaded from the archive of repository ", pinnedPackageRepository,
+			". [", dependencyChain, "]",
+		)
+		pinnedPackage.Source = "Repository"
+		addPackage(pinnedPackage, pinnedPackageRepository, exactVersion)
+		return
+	}
+	versionOperator, versionValue = GetMissingPackageConstraint(
+		name, versionOperator, versionValue, override, searchedRepositories, packagesFiles,
+	)
 	ProcessMissingPackage(
 		indentation, name, versionOperator, versionValue, dependencyType,
 		allowedMissingDependencyTypes, fatalMissingPackageVersions,
@@ -158,6 +214,68 @@ func ResolveDependenciesRecursively(outputList *[]PackageDescription, name strin
 	)
 }
 
+// CheckIfRepositoryPackageAccepted checks whether the package p found in the repository can be locked
+// to satisfy the requirement versionOperator versionValue, or the version constraints of the override
+// if the package has been overridden. The override takes precedence over the requirement.
+// Warnings are shown if the package can't be used, or if it doesn't come from the top repository.
+func CheckIfRepositoryPackageAccepted(indentation string, p PackageDescription, repository string,
+	repositoryList []string, versionOperator string, versionValue string,
+	packageOverrides map[string]PackageOverride, dependencyChain string) bool {
+	override, overridden := packageOverrides[p.Package]
+	if repository != repositoryList[0] && override.RepositoryURL == "" {
+		log.Warn(indentation, p.Package, " not found in top repository. [", dependencyChain, "]")
+	}
+	if !overridden {
+		if !CheckIfVersionSufficient(p.Version, versionOperator, versionValue) {
+			// Check if package in the repository is available in sufficient version.
+			log.Warn(
+				indentation, p.Package, " in repository ", repository,
+				" is available in version ", p.Version,
+				" which is insufficient according to requirement ",
+				versionOperator, " ", versionValue, ". [", dependencyChain, "]",
+			)
+			return false
+		}
+		return true
+	}
+	if !CheckIfVersionConstraintsSatisfied(p.Version, override.VersionConstraints) {
+		log.Warn(
+			indentation, p.Package, " in repository ", repository,
+			" is available in version ", p.Version,
+			" which doesn't match the override ", override.Version, ". [", dependencyChain, "]",
+		)
+		return false
+	}
+	if !CheckIfVersionSufficient(p.Version, versionOperator, versionValue) {
+		log.Warn(
+			indentation, "Override forces ", p.Package, " version ", p.Version,
+			" despite the requirement ", versionOperator, " ", versionValue,
+			". [", dependencyChain, "]",
+		)
+	}
+	return true
+}
+
+// ReportExcludedPackages shows the list of excluded packages together with the packages
+// which depend on them.
+func ReportExcludedPackages(excludedPackageDependants map[string][]string) {
+	if len(excludedPackageDependants) == 0 {
+		return
+	}
+	// As the map is not sorted, in order to generate predictable output
+	// we have to process the package names in sorted order.
+	var excludedPackageNames []string
+	for k := range excludedPackageDependants {
+		excludedPackageNames = append(excludedPackageNames, k)
+	}
+	sort.Strings(excludedPackageNames)
+	excludedString := "Packages excluded from the renv.lock:\n"
+	for _, k := range excludedPackageNames {
+		excludedString += k + " (required by " + strings.Join(excludedPackageDependants[k], ", ") + ")\n"
+	}
+	log.Warn(excludedString)
+}
+
 // ProcessMissingPackage saves information about missing packages (dependencies) and their versions.
 // This information is later reported to the user, together with optionally exiting the application
 // with failed status, depending on the types of missing dependencies and the configuration
@@ -209,22 +327,196 @@ func CheckIfBasePackage(name string) bool {
 	return stringInSlice(name, basePackages)
 }
 
+// GetRequirements returns the sorted list of the names of hard dependencies (Depends, Imports, LinkingTo)
+// from the list of package dependencies, excluding base packages.
+func GetRequirements(dependencies []Dependency) []string {
+	requirements := []string{}
+	for _, d := range dependencies {
+		if (d.DependencyType == depends || d.DependencyType == imports || d.DependencyType == linkingTo) &&
+			!CheckIfBasePackage(d.DependencyName) && !stringInSlice(d.DependencyName, requirements) {
+			requirements = append(requirements, d.DependencyName)
+		}
+	}
+	sort.Strings(requirements)
+	return requirements
+}
+
+// recommendedPackages are included in R installations by default, but are also distributed
+// via package repositories.
+var recommendedPackages = []string{
+	"KernSmooth", "MASS", "Matrix", "boot", "class", "cluster", "codetools", "foreign",
+	"lattice", "mgcv", "nlme", "nnet", "rpart", "spatial", "survival",
+}
+
+// CheckIfRecommendedPackage checks whether the package is an R recommended package.
+func CheckIfRecommendedPackage(name string) bool {
+	return stringInSlice(name, recommendedPackages)
+}
+
+// GetRecommendedPackagesPath returns the value of the "Path" field under which the PACKAGES file
+// lists the recommended packages bundled with rVersion, e.g. '4.4.0/Recommended'. If rVersion
+// is specified only partially (e.g. '4.4'), the path corresponding to the latest matching
+// R version is returned. Empty string is returned if no path matches rVersion.
+func GetRecommendedPackagesPath(packagesFile PackagesFile, rVersion string) string {
+	var matchingPath, matchingVersion string
+	rVersionComponents := strings.FieldsFunc(rVersion, splitVersion)
+	for path := range packagesFile.PathPackages {
+		if !strings.HasSuffix(path, "/Recommended") {
+			continue
+		}
+		pathVersion := strings.TrimSuffix(path, "/Recommended")
+		pathVersionComponents := strings.FieldsFunc(pathVersion, splitVersion)
+		if len(pathVersionComponents) < len(rVersionComponents) ||
+			strings.Join(pathVersionComponents[:len(rVersionComponents)], ".") !=
+				strings.Join(rVersionComponents, ".") {
+			continue
+		}
+		if matchingVersion == "" || CheckIfVersionSufficient(pathVersion, ">", matchingVersion) {
+			matchingPath = path
+			matchingVersion = pathVersion
+		}
+	}
+	return matchingPath
+}
+
+// ReplacePackageEntry replaces the entries of package p in the list of packages with p,
+// or appends p to the list if the package is not present there.
+func ReplacePackageEntry(packages []PackageDescription, p PackageDescription) []PackageDescription {
+	var replaced bool
+	for i := range packages {
+		if packages[i].Package == p.Package {
+			packages[i] = p
+			replaced = true
+		}
+	}
+	if !replaced {
+		packages = append(packages, p)
+	}
+	return packages
+}
+
+// ProcessRecommendedPackages prepares the processing of R recommended packages according to
+// recommendedPackagesMode:
+// * 'repository' - recommended packages are treated as any other package and are
+// downloaded from package repositories in their latest versions,
+// * 'installed' - recommended packages are assumed to be installed in the target R installation
+// (rVersion), in versions listed in the "Path" subdirectory entries of PACKAGES files,
+// * 'path' - recommended packages are downloaded from package repositories, in versions
+// listed in the "Path" subdirectory entries of PACKAGES files, matching rVersion.
+// It returns a map from package name to package version containing packages assumed to be installed.
+// In 'path' mode, packagesFiles are modified so that only the "Path" entries of recommended
+// packages are taken into account.
+func ProcessRecommendedPackages(recommendedPackagesMode string, rVersion string,
+	packagesFiles map[string]PackagesFile, repositoryList []string) map[string]string {
+	installedPackages := make(map[string]string)
+	switch recommendedPackagesMode {
+	case recommendedPackagesFromRepository, "":
+		return installedPackages
+	case recommendedPackagesInstalled, recommendedPackagesFromPath:
+		if rVersion == "" {
+			log.Fatal("Please specify --rVersion to use --recommendedPackages ", recommendedPackagesMode, ".")
+		}
+	default:
+		log.Fatal("Unknown --recommendedPackages mode: ", recommendedPackagesMode,
+			". Please use one of: repository, installed, path.")
+	}
+	for _, r := range repositoryList {
+		packagesFile := packagesFiles[r]
+		path := GetRecommendedPackagesPath(packagesFile, rVersion)
+		if path == "" {
+			log.Debug("No recommended packages for R ", rVersion, " found in repository ", r)
+			continue
+		}
+		log.Debug("Using recommended packages from ", r, "/", path)
+		for _, p := range packagesFile.PathPackages[path] {
+			if !CheckIfRecommendedPackage(p.Package) {
+				continue
+			}
+			if recommendedPackagesMode == recommendedPackagesInstalled {
+				// Repositories are processed in descending priority order.
+				if _, ok := installedPackages[p.Package]; !ok {
+					installedPackages[p.Package] = p.Version
+				}
+				continue
+			}
+			// Replace the package entry in the top-level PACKAGES file with the entry
+			// from "Path" subdirectory.
+			packagesFile.Packages = ReplacePackageEntry(packagesFile.Packages, p)
+		}
+		packagesFiles[r] = packagesFile
+	}
+	if recommendedPackagesMode == recommendedPackagesInstalled {
+		// Recommended packages not found in any of the repositories are assumed
+		// to be installed in an unknown version.
+		for _, p := range recommendedPackages {
+			if _, ok := installedPackages[p]; !ok {
+				log.Warn("Version of recommended package ", p, " bundled with R ", rVersion, " is unknown.")
+				installedPackages[p] = ""
+			}
+		}
+	}
+	return installedPackages
+}
+
 // CheckIfSkipDependency checks if processing of the package (dependency) should be skipped.
-// Dependency should be skipped if it is a base R package, or has already been added to output
+// Dependency should be skipped if it is a base R package, if it is present in installedPackages
+// in a sufficient version, if it matches excludedPackagesRegex, or has already been added to output
 // package list (later used to generate the renv.lock).
+// Overridden dependencies already added to the output package list are always skipped,
+// as their version is determined by the override.
 func CheckIfSkipDependency(indentation string, packageName string, dependencyName string,
 	versionOperator string, versionValue string, outputList *[]PackageDescription,
+	packageOverrides map[string]PackageOverride, excludedPackagesRegex string,
+	excludedPackageDependants map[string][]string, installedPackages map[string]string,
 	dependencyChain string) bool {
 	if CheckIfBasePackage(dependencyName) {
 		log.Trace(indentation, "Skipping package ", dependencyName, " as it is a base R package.")
 		return true
 	}
+	if installedVersion, ok := installedPackages[dependencyName]; ok {
+		// Installed package version may be unknown, in which case it's assumed to be sufficient.
+		if installedVersion == "" || CheckIfVersionSufficient(installedVersion, versionOperator, versionValue) {
+			log.Trace(indentation, "Skipping package ", dependencyName, " as it is installed with R.")
+			return true
+		}
+		log.Warn(
+			indentation, "Version ", installedVersion, " of ", dependencyName,
+			" installed with R is insufficient as ", packageName, " requires ", dependencyName, " ",
+			versionOperator, " ", versionValue, " so it will be downloaded from a repository. [",
+			dependencyChain, "]",
+		)
+	}
+	if excludedPackagesRegex != "" {
+		match, err := regexp.MatchString(excludedPackagesRegex, dependencyName)
+		checkError(err)
+		if match {
+			log.Debug(indentation, "Skipping package ", dependencyName, " as it has been excluded. [",
+				dependencyChain, " → ", dependencyName, "]")
+			if !stringInSlice(packageName, excludedPackageDependants[dependencyName]) {
+				excludedPackageDependants[dependencyName] = append(
+					excludedPackageDependants[dependencyName], packageName,
+				)
+			}
+			return true
+		}
+	}
 	// Go through the list of dependencies added to the output list previously, to check
 	// if it contains a dependency required by the currently processed package but in a version
 	// that is too low.
 	for i := 0; i < len(*outputList); i++ {
 		if dependencyName == (*outputList)[i].Package {
 			// Dependency found on the output list.
+			if _, ok := packageOverrides[dependencyName]; ok {
+				if !CheckIfVersionSufficient((*outputList)[i].Version, versionOperator, versionValue) {
+					log.Warn(
+						indentation, "Output list already contains ", dependencyName, " version ",
+						(*outputList)[i].Version, " forced by the override, although ", packageName,
+						" requires ", dependencyName, " ", versionOperator, " ", versionValue,
+						". [", dependencyChain, "]",
+					)
+				}
+				return true
+			}
 			if CheckIfVersionSufficient((*outputList)[i].Version, versionOperator, versionValue) {
 				var requirementMessage string
 				if versionOperator != "" && versionValue != "" {
@@ -266,7 +558,7 @@ func splitVersion(r rune) bool {
 }
 
 // CheckIfVersionSufficient checks if availableVersionValue fulfills the requirement
-// expressed by versionOperator ('>=' or '>') and requiredVersionValue.
+// expressed by versionOperator ('>=', '>', '==', '<=' or '<') and requiredVersionValue.
 func CheckIfVersionSufficient(availableVersionValue string, versionOperator string,
 	requiredVersionValue string) bool {
 	// Check if there are any version requirements at all.
@@ -315,7 +607,7 @@ func CheckIfVersionSufficient(availableVersionValue string, versionOperator stri
 		}
 	}
 
-	if versionOperator != ">" && versionOperator != ">=" {
+	if !stringInSlice(versionOperator, []string{">", ">=", exactVersionOperator, "<=", "<"}) {
 		log.Error("Unknown version constraint operator: ", versionOperator)
 	}
 	return strings.Contains(versionOperator, available)
@@ -91,6 +91,12 @@ func Test_CheckIfVersionSufficient(t *testing.T) {
 	assert.True(t, CheckIfVersionSufficient("1.2.3.4", ">", "1"))
 	assert.False(t, CheckIfVersionSufficient("1.2.3.4", ">=", "2"))
 	assert.False(t, CheckIfVersionSufficient("1.2.3.4", ">", "2"))
+	assert.True(t, CheckIfVersionSufficient("1.2.3", "==", "1.2.3"))
+	assert.False(t, CheckIfVersionSufficient("1.2.4", "==", "1.2.3"))
+	assert.True(t, CheckIfVersionSufficient("1.2.3", "<", "1.3"))
+	assert.False(t, CheckIfVersionSufficient("1.3", "<", "1.3"))
+	assert.True(t, CheckIfVersionSufficient("1.3", "<=", "1.3"))
+	assert.False(t, CheckIfVersionSufficient("1.3.1", "<=", "1.3"))
 }
 
 func Test_ConstructOutputPackageList(t *testing.T) {
@@ -126,7 +132,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package4",
@@ -146,14 +152,14 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package11",
 				"0.7.8",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package14",
@@ -173,30 +179,31 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"2.2",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package16",
 				"2.4.5",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package6",
 				"3.0.1",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package10",
 				"3.0.2",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
 	packagesFiles["https://repo2.example.com/ExampleRepo2"] = PackagesFile{
 		[]PackageDescription{
@@ -224,21 +231,21 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package5",
 				"3.2.0",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package7",
 				"1.6.2",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package9",
@@ -252,30 +259,31 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"3.6",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package11",
 				"5.4.7",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package12",
 				"1.2.3",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package15",
 				"3.3.4.5",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
 	packagesFiles["https://repo3.example.com/ExampleRepo3"] = PackagesFile{
 		[]PackageDescription{
@@ -284,9 +292,10 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"1.9.2",
 				"", "",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
 	outputPackageList := ConstructOutputPackageList(
 		[]PackageDescription{
@@ -339,7 +348,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"1.0.0",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package2",
@@ -390,14 +399,14 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 						"",
 					},
 				},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
 		packagesFiles, repositoryList,
 		// Let the generation of renv.lock proceed, despite 'nonExistentPackage'
 		// and 'nonExistentPackage2' (dependency type LinkingTo) not being found
 		// in any repository.
-		[]string{"LinkingTo"},
+		[]string{"LinkingTo"}, map[string]PackageOverride{}, "", map[string]string{},
 	)
 	assert.Equal(t, outputPackageList,
 		[]PackageDescription{
@@ -407,7 +416,8 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"GitHub",
 				"",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "",
+				[]string{"nonExistentPackage", "nonExistentPackage2", "package3", "package4", "package6"}, "", nil,
 			},
 			{
 				"package2",
@@ -415,7 +425,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"GitHub",
 				"",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{"package10", "package7", "package8"}, "", nil,
 			},
 			{
 				"package3",
@@ -423,7 +433,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo1.example.com/ExampleRepo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{"package11", "package12"}, "", nil,
 			},
 			{
 				// package11 removed from here
@@ -432,7 +442,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				// However afterwards, package4 requested package11 >= 4.5
 				// so it had to be retrieved from repo2.
 				// The reference to repo1 was overwritten here.
-				"", "", "", "", []Dependency{}, "", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", []Dependency{}, "", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package12",
@@ -440,7 +450,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package4",
@@ -448,7 +458,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{"package11", "package12", "package14"}, "", nil,
 			},
 			{
 				"package11",
@@ -456,7 +466,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package14",
@@ -464,7 +474,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo1.example.com/ExampleRepo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{"package15", "package16"}, "", nil,
 			},
 			{
 				"package15",
@@ -472,7 +482,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package16",
@@ -480,7 +490,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo1.example.com/ExampleRepo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package5",
@@ -488,7 +498,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package6",
@@ -496,7 +506,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo1.example.com/ExampleRepo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package7",
@@ -504,7 +514,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package8",
@@ -512,7 +522,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo3.example.com/ExampleRepo3",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package9",
@@ -520,7 +530,7 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo2.example.com/ExampleRepo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package10",
@@ -528,8 +538,342 @@ func Test_ConstructOutputPackageList(t *testing.T) {
 				"Repository",
 				"https://repo1.example.com/ExampleRepo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
 	)
 }
+
+func Test_ConstructOutputPackageListWithOverrides(t *testing.T) {
+	var repositoryList = []string{
+		"https://repo1.example.com/ExampleRepo1",
+		"https://repo2.example.com/ExampleRepo2",
+	}
+	packagesFiles := make(map[string]PackagesFile)
+	packagesFiles["https://repo1.example.com/ExampleRepo1"] = PackagesFile{
+		[]PackageDescription{
+			{
+				"package3", "2.0.0", "", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			{
+				"package4", "1.5", "", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			{
+				"package5", "1.0", "", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+		nil,
+	}
+	packagesFiles["https://repo2.example.com/ExampleRepo2"] = PackagesFile{
+		[]PackageDescription{
+			{
+				"package3", "1.0.0", "", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			{
+				"package4", "1.2", "", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+		nil,
+	}
+	packageOverrides := map[string]PackageOverride{
+		"package3": {
+			Package: "package3", Version: "1.0.0",
+			VersionConstraints: []DependencyVersion{{"==", "1.0.0"}},
+		},
+		"package4": {
+			Package: "package4", Repository: "Repo2",
+			RepositoryURL: "https://repo2.example.com/ExampleRepo2",
+		},
+		"package5": {
+			Package: "package5", Version: "0.9",
+			VersionConstraints:     []DependencyVersion{{"==", "0.9"}},
+			ArchivedRepositoryURLs: []string{"https://repo1.example.com/ExampleRepo1"},
+		},
+		"package6": {
+			Package: "package6", Remote: "https://raw.githubusercontent.com/org1/package6/main/DESCRIPTION",
+			GitPackage: PackageDescription{
+				"package6", "0.1.0", "GitHub", "",
+				[]Dependency{{"Imports", "package5", "", ""}},
+				"github", "api.github.com", "org1", "package6", "", "main", "aaabbbccc",
+				[]string{}, "", nil,
+			},
+		},
+	}
+	outputPackageList := ConstructOutputPackageList(
+		[]PackageDescription{
+			{
+				"package1", "1.2.3", "GitHub", "",
+				[]Dependency{
+					{"Imports", "package3", ">=", "1.5"},
+					{"Imports", "package4", "", ""},
+					{"Imports", "package6", "", ""},
+				},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+		packagesFiles, repositoryList, []string{}, packageOverrides, "", map[string]string{},
+	)
+	assert.Equal(t, outputPackageList,
+		[]PackageDescription{
+			{
+				"package1", "1.2.3", "GitHub", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package3", "package4", "package6"}, "", nil,
+			},
+			{
+				"package3", "1.0.0", "Repository", "https://repo2.example.com/ExampleRepo2", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			{
+				"package4", "1.2", "Repository", "https://repo2.example.com/ExampleRepo2", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			{
+				"package6", "0.1.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package6", "", "main", "aaabbbccc",
+				[]string{"package5"}, "", nil,
+			},
+			{
+				"package5", "0.9", "Repository", "https://repo1.example.com/ExampleRepo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	)
+}
+
+func Test_ResolveDependenciesRecursivelyMissingOverride(t *testing.T) {
+	repositoryList := []string{"https://repo1.example.com/ExampleRepo1"}
+	packagesFiles := map[string]PackagesFile{
+		"https://repo1.example.com/ExampleRepo1": {
+			[]PackageDescription{
+				{
+					"package3", "2.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	// The pinned version is higher than the one available in the repository,
+	// so it can't be downloaded from the repository archive.
+	packageOverrides := map[string]PackageOverride{
+		"package3": {
+			Package: "package3", Version: "3.0.0",
+			VersionConstraints:     []DependencyVersion{{"==", "3.0.0"}},
+			ArchivedRepositoryURLs: []string{},
+		},
+	}
+	var outputList []PackageDescription
+	fatalMissingPackageVersions := make(map[string]DependencyVersion)
+	nonFatalMissingPackageVersions := make(map[string]DependencyVersion)
+	ResolveDependenciesRecursively(
+		&outputList, "package3", ">=", "1.5", "Imports", []string{"Imports"}, repositoryList,
+		packagesFiles, packageOverrides, "", map[string][]string{}, map[string]string{}, 0,
+		fatalMissingPackageVersions, nonFatalMissingPackageVersions, "package1",
+	)
+	assert.Equal(t, len(outputList), 0)
+	assert.Equal(t, len(fatalMissingPackageVersions), 0)
+	assert.Equal(t, nonFatalMissingPackageVersions, map[string]DependencyVersion{
+		"package3": {"==", "3.0.0"},
+	})
+}
+
+func Test_GetExcludedPackagesRegex(t *testing.T) {
+	assert.Equal(t, GetExcludedPackagesRegex(""), "")
+	assert.Equal(t, GetExcludedPackagesRegex("RDCOMClient,*Win*"), `^RDCOMClient$|^.*Win.*$`)
+}
+
+func Test_CheckIfSkipDependencyExcludedPackages(t *testing.T) {
+	var outputList []PackageDescription
+	excludedPackageDependants := make(map[string][]string)
+	excludedPackagesRegex := GetPackageRegex("RDCOMClient,*Win*")
+	assert.True(t, CheckIfSkipDependency("", "package1", "RDCOMClient", "", "", &outputList,
+		map[string]PackageOverride{}, excludedPackagesRegex, excludedPackageDependants, map[string]string{}, "package1"))
+	assert.True(t, CheckIfSkipDependency("", "package2", "RDCOMClient", "", "", &outputList,
+		map[string]PackageOverride{}, excludedPackagesRegex, excludedPackageDependants, map[string]string{}, "package2"))
+	assert.True(t, CheckIfSkipDependency("", "package2", "someWinPackage", ">=", "1.0", &outputList,
+		map[string]PackageOverride{}, excludedPackagesRegex, excludedPackageDependants, map[string]string{}, "package2"))
+	assert.False(t, CheckIfSkipDependency("", "package2", "package3", "", "", &outputList,
+		map[string]PackageOverride{}, excludedPackagesRegex, excludedPackageDependants, map[string]string{}, "package2"))
+	assert.Equal(t, excludedPackageDependants, map[string][]string{
+		"RDCOMClient":    {"package1", "package2"},
+		"someWinPackage": {"package2"},
+	})
+}
+
+func Test_ConstructOutputPackageListWithExcludedPackages(t *testing.T) {
+	packagesFiles := make(map[string]PackagesFile)
+	packagesFiles["https://repo1.example.com/ExampleRepo1"] = PackagesFile{
+		[]PackageDescription{
+			{
+				"package3", "2.0.0", "", "",
+				[]Dependency{{"Imports", "windowsOnlyPackage", "", ""}},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+		nil,
+	}
+	// Neither windowsOnlyPackage nor proprietaryPackage exist in the repository,
+	// but generation of the package list doesn't fail since they're excluded.
+	outputPackageList := ConstructOutputPackageList(
+		[]PackageDescription{
+			{
+				"package1", "1.2.3", "GitHub", "",
+				[]Dependency{
+					{"Imports", "package3", "", ""},
+					{"Suggests", "proprietaryPackage", "", ""},
+				},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+		packagesFiles, []string{"https://repo1.example.com/ExampleRepo1"}, []string{},
+		map[string]PackageOverride{}, "windows*,proprietaryPackage", map[string]string{},
+	)
+	assert.Equal(t, outputPackageList,
+		[]PackageDescription{
+			{
+				"package1", "1.2.3", "GitHub", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package3"}, "", nil,
+			},
+			{
+				"package3", "2.0.0", "Repository", "https://repo1.example.com/ExampleRepo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"windowsOnlyPackage"}, "", nil,
+			},
+		},
+	)
+}
+
+func Test_GetRequirements(t *testing.T) {
+	requirements := GetRequirements([]Dependency{
+		{"Depends", "R", ">=", "4.0"},
+		{"Depends", "shiny", "", ""},
+		{"Imports", "utils", "", ""},
+		{"Imports", "dplyr", ">=", "1.0.0"},
+		{"LinkingTo", "cpp11", "", ""},
+		{"LinkingTo", "dplyr", "", ""},
+		{"Suggests", "testthat", "", ""},
+		{"Enhances", "data.table", "", ""},
+	})
+	assert.Equal(t, requirements, []string{"cpp11", "dplyr", "shiny"})
+}
+
+func Test_GetRecommendedPackagesPath(t *testing.T) {
+	packagesFile := PackagesFile{
+		[]PackageDescription{},
+		map[string][]PackageDescription{
+			"4.3.2/Recommended": {},
+			"4.4.0/Recommended": {},
+			"4.4.1/Recommended": {},
+			"4.4.1/Other":       {},
+		},
+	}
+	assert.Equal(t, GetRecommendedPackagesPath(packagesFile, "4.4.0"), "4.4.0/Recommended")
+	assert.Equal(t, GetRecommendedPackagesPath(packagesFile, "4.4"), "4.4.1/Recommended")
+	assert.Equal(t, GetRecommendedPackagesPath(packagesFile, "4.3"), "4.3.2/Recommended")
+	assert.Equal(t, GetRecommendedPackagesPath(packagesFile, "4.2"), "")
+}
+
+func Test_ReplacePackageEntry(t *testing.T) {
+	packages := []PackageDescription{
+		{
+			"Matrix", "1.6-5", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+	}
+	packages = ReplacePackageEntry(packages, PackageDescription{
+		"Matrix", "1.6-1", "", "", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "", nil,
+	})
+	packages = ReplacePackageEntry(packages, PackageDescription{
+		"MASS", "7.3-60", "", "", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "", nil,
+	})
+	assert.Equal(t, packages, []PackageDescription{
+		{
+			"Matrix", "1.6-1", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+		{
+			"MASS", "7.3-60", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+	})
+}
+
+func Test_ProcessRecommendedPackages(t *testing.T) {
+	getPackagesFiles := func() map[string]PackagesFile {
+		packagesFiles := make(map[string]PackagesFile)
+		packagesFiles["https://repo1.example.com/ExampleRepo1"] = PackagesFile{
+			[]PackageDescription{
+				{
+					"Matrix", "1.7-1", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"package3", "1.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			map[string][]PackageDescription{
+				"4.4.0/Recommended": {
+					{
+						"Matrix", "1.7-0", "", "", []Dependency{},
+						"", "", "", "", "", "", "", []string{}, "", nil,
+					},
+					{
+						"MASS", "7.3-60.2", "", "", []Dependency{},
+						"", "", "", "", "", "", "", []string{}, "", nil,
+					},
+				},
+			},
+		}
+		return packagesFiles
+	}
+	repositoryList := []string{"https://repo1.example.com/ExampleRepo1"}
+
+	packagesFiles := getPackagesFiles()
+	installedPackages := ProcessRecommendedPackages("repository", "", packagesFiles, repositoryList)
+	assert.Empty(t, installedPackages)
+	assert.Equal(t, packagesFiles, getPackagesFiles())
+
+	installedPackages = ProcessRecommendedPackages("installed", "4.4", packagesFiles, repositoryList)
+	assert.Equal(t, installedPackages["Matrix"], "1.7-0")
+	assert.Equal(t, installedPackages["MASS"], "7.3-60.2")
+	assert.Equal(t, installedPackages["survival"], "")
+	assert.Len(t, installedPackages, len(recommendedPackages))
+	assert.Equal(t, packagesFiles, getPackagesFiles())
+
+	installedPackages = ProcessRecommendedPackages("path", "4.4.0", packagesFiles, repositoryList)
+	assert.Empty(t, installedPackages)
+	assert.Equal(t, packagesFiles["https://repo1.example.com/ExampleRepo1"].Packages, []PackageDescription{
+		{
+			"Matrix", "1.7-0", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+		{
+			"package3", "1.0.0", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+		{
+			"MASS", "7.3-60.2", "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		},
+	})
+}
+
+func Test_CheckIfSkipDependencyInstalledPackages(t *testing.T) {
+	var outputList []PackageDescription
+	installedPackages := map[string]string{"Matrix": "1.7-0", "survival": ""}
+	assert.True(t, CheckIfSkipDependency("", "package1", "Matrix", ">=", "1.6", &outputList,
+		map[string]PackageOverride{}, "", map[string][]string{}, installedPackages, "package1"))
+	assert.False(t, CheckIfSkipDependency("", "package1", "Matrix", ">=", "1.7-1", &outputList,
+		map[string]PackageOverride{}, "", map[string][]string{}, installedPackages, "package1"))
+	assert.True(t, CheckIfSkipDependency("", "package1", "survival", ">=", "3.7", &outputList,
+		map[string]PackageOverride{}, "", map[string][]string{}, installedPackages, "package1"))
+	assert.False(t, CheckIfSkipDependency("", "package1", "MASS", "", "", &outputList,
+		map[string]PackageOverride{}, "", map[string][]string{}, installedPackages, "package1"))
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const diffFormatText = "text"
+const diffFormatMarkdown = "markdown"
+const diffFormatJSON = "json"
+
+// Exit code used by --dryRun when updates are available.
+const updatesAvailableExitCode = 3
+
+var diffFormat string
+
+func newDiffCommand() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff <old renv.lock> <new renv.lock>",
+		Short: "Compare two renv.lock files",
+		Long: `Compare two renv.lock files and list the packages which have been added, removed,
+upgraded or downgraded, as well as the packages for which the source, the repository
+or the git commit SHA has changed.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			setLogLevel()
+			lockfileDiff := GetLockfileDiff(ReadRenvLock(args[0]), ReadRenvLock(args[1]))
+			fmt.Print(FormatLockfileDiff(lockfileDiff, diffFormat))
+		},
+	}
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "o", diffFormatText,
+		"Output format: 'text', 'markdown' (e.g. for pull request comments) or 'json'.")
+	return diffCmd
+}
+
+// GetPackageSourceDescription returns a human-readable description of the package source,
+// e.g. 'Repository: CRAN' or 'GitHub: org/repo/subdirectory@main'.
+func GetPackageSourceDescription(p PackageDescription) string {
+	if p.Source != GitHub && p.Source != GitLab {
+		return p.Source + ": " + p.Repository
+	}
+	sourceDescription := p.Source + ": " + p.RemoteUsername + "/" + p.RemoteRepo
+	if p.RemoteSubdir != "" {
+		sourceDescription += "/" + p.RemoteSubdir
+	}
+	if p.RemoteRef != "" {
+		sourceDescription += "@" + p.RemoteRef
+	}
+	return sourceDescription
+}
+
+// GetLockfileDiff compares the packages in oldRenvLock and newRenvLock.
+// Each list in the returned structure is sorted by package name.
+func GetLockfileDiff(oldRenvLock RenvLock, newRenvLock RenvLock) LockfileDiff {
+	lockfileDiff := LockfileDiff{
+		[]PackageChange{}, []PackageChange{}, []PackageChange{}, []PackageChange{}, []PackageChange{},
+		[]PackageChange{},
+	}
+	var packageNames []string
+	for k := range oldRenvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	for k := range newRenvLock.Packages {
+		if _, ok := oldRenvLock.Packages[k]; !ok {
+			packageNames = append(packageNames, k)
+		}
+	}
+	sort.Strings(packageNames)
+	for _, k := range packageNames {
+		oldPackage, oldOk := oldRenvLock.Packages[k]
+		newPackage, newOk := newRenvLock.Packages[k]
+		change := PackageChange{
+			k, oldPackage.Version, newPackage.Version, "", "", oldPackage.RemoteSha, newPackage.RemoteSha,
+		}
+		if oldOk {
+			change.OldSource = GetPackageSourceDescription(oldPackage)
+		}
+		if newOk {
+			change.NewSource = GetPackageSourceDescription(newPackage)
+		}
+		switch {
+		case !oldOk:
+			lockfileDiff.Added = append(lockfileDiff.Added, change)
+			continue
+		case !newOk:
+			lockfileDiff.Removed = append(lockfileDiff.Removed, change)
+			continue
+		case CheckIfVersionSufficient(newPackage.Version, ">", oldPackage.Version):
+			lockfileDiff.Upgraded = append(lockfileDiff.Upgraded, change)
+		case CheckIfVersionSufficient(newPackage.Version, "<", oldPackage.Version):
+			lockfileDiff.Downgraded = append(lockfileDiff.Downgraded, change)
+		}
+		if change.OldSource != change.NewSource {
+			lockfileDiff.SourceChanged = append(lockfileDiff.SourceChanged, change)
+		}
+		if change.OldSha != "" && change.NewSha != "" && change.OldSha != change.NewSha {
+			lockfileDiff.ShaChanged = append(lockfileDiff.ShaChanged, change)
+		}
+	}
+	return lockfileDiff
+}
+
+// CheckIfLockfileDiffEmpty checks whether there are no changes in lockfileDiff.
+func CheckIfLockfileDiffEmpty(lockfileDiff LockfileDiff) bool {
+	return len(lockfileDiff.Added) == 0 && len(lockfileDiff.Removed) == 0 && len(lockfileDiff.Upgraded) == 0 &&
+		len(lockfileDiff.Downgraded) == 0 && len(lockfileDiff.SourceChanged) == 0 &&
+		len(lockfileDiff.ShaChanged) == 0
+}
+
+// FormatLockfileDiff returns the lockfileDiff in the requested format: text, markdown or json.
+func FormatLockfileDiff(lockfileDiff LockfileDiff, format string) string {
+	if format == diffFormatJSON {
+		var output bytes.Buffer
+		encoder := json.NewEncoder(&output)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(lockfileDiff)
+		checkError(err)
+		return output.String()
+	}
+	if format != diffFormatText && format != diffFormatMarkdown {
+		log.Fatal("Unknown output format: ", format, ". Please use one of: ",
+			diffFormatText, ", ", diffFormatMarkdown, ", ", diffFormatJSON, ".")
+	}
+	if CheckIfLockfileDiffEmpty(lockfileDiff) {
+		return "No changes.\n"
+	}
+	// Each section consists of a title, the column names, and a function returning the column values.
+	sections := []struct {
+		title   string
+		changes []PackageChange
+		columns []string
+		values  func(c PackageChange) []string
+	}{
+		{"Added packages", lockfileDiff.Added, []string{"Package", "Version", "Source"},
+			func(c PackageChange) []string { return []string{c.NewVersion, c.NewSource} }},
+		{"Removed packages", lockfileDiff.Removed, []string{"Package", "Version", "Source"},
+			func(c PackageChange) []string { return []string{c.OldVersion, c.OldSource} }},
+		{"Upgraded packages", lockfileDiff.Upgraded, []string{"Package", "Old version", "New version"},
+			func(c PackageChange) []string { return []string{c.OldVersion, c.NewVersion} }},
+		{"Downgraded packages", lockfileDiff.Downgraded, []string{"Package", "Old version", "New version"},
+			func(c PackageChange) []string { return []string{c.OldVersion, c.NewVersion} }},
+		{"Source changes", lockfileDiff.SourceChanged, []string{"Package", "Old source", "New source"},
+			func(c PackageChange) []string { return []string{c.OldSource, c.NewSource} }},
+		{"Git SHA changes", lockfileDiff.ShaChanged, []string{"Package", "Old SHA", "New SHA"},
+			func(c PackageChange) []string { return []string{c.OldSha, c.NewSha} }},
+	}
+	var output strings.Builder
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		if format == diffFormatMarkdown {
+			output.WriteString("### " + section.title + "\n\n")
+			output.WriteString("| " + strings.Join(section.columns, " | ") + " |\n")
+			output.WriteString("|" + strings.Repeat(" --- |", len(section.columns)) + "\n")
+			for _, c := range section.changes {
+				output.WriteString("| " + strings.Join(append([]string{c.Package}, section.values(c)...), " | ") + " |\n")
+			}
+			output.WriteString("\n")
+			continue
+		}
+		output.WriteString(section.title + ":\n")
+		for _, c := range section.changes {
+			values := section.values(c)
+			if section.columns[1] == "Version" {
+				output.WriteString("  " + c.Package + " " + values[0] + " (" + values[1] + ")\n")
+			} else {
+				output.WriteString("  " + c.Package + ": " + values[0] + " → " + values[1] + "\n")
+			}
+		}
+	}
+	return output.String()
+}
+
+// ReportDryRun prints the changes between the lockfile read from inputFileName and the updated renvLock,
+// and saves them as JSON to outputFileName (if provided). It returns true if any packages would be updated.
+func ReportDryRun(inputFileName string, renvLock RenvLock, outputFileName string) bool {
+	lockfileDiff := GetLockfileDiff(ReadRenvLock(inputFileName), renvLock)
+	fmt.Print(FormatLockfileDiff(lockfileDiff, diffFormatText))
+	if outputFileName != "" {
+		writeJSON(outputFileName, lockfileDiff)
+	}
+	return !CheckIfLockfileDiffEmpty(lockfileDiff)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getDiffTestLockfiles() (RenvLock, RenvLock) {
+	oldRenvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package2": {
+				"package2", "2.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package3": {
+				"package3", "3.1.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package4": {
+				"package4", "0.1.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package4", "", "main", "aaa111", []string{}, "", nil,
+			},
+		},
+	}
+	newRenvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0.1", "Repository", "BioC", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package3": {
+				"package3", "3.0.5", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package4": {
+				"package4", "0.1.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package4", "", "main", "bbb222", []string{}, "", nil,
+			},
+			"package5": {
+				"package5", "5.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	}
+	return oldRenvLock, newRenvLock
+}
+
+func Test_GetPackageSourceDescription(t *testing.T) {
+	assert.Equal(t, GetPackageSourceDescription(PackageDescription{
+		"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "", nil,
+	}), "Repository: CRAN")
+	assert.Equal(t, GetPackageSourceDescription(PackageDescription{
+		"package2", "1.0.0", "GitLab", "", []Dependency{},
+		"gitlab", "https://gitlab.example.com", "group1/group2", "package2", "subdirectory", "v1.0.0", "aaa111",
+		[]string{}, "", nil,
+	}), "GitLab: group1/group2/package2/subdirectory@v1.0.0")
+}
+
+func Test_GetLockfileDiff(t *testing.T) {
+	oldRenvLock, newRenvLock := getDiffTestLockfiles()
+	lockfileDiff := GetLockfileDiff(oldRenvLock, newRenvLock)
+	assert.Equal(t, lockfileDiff, LockfileDiff{
+		[]PackageChange{{"package5", "", "5.0", "", "Repository: CRAN", "", ""}},
+		[]PackageChange{{"package2", "2.0.0", "", "Repository: CRAN", "", "", ""}},
+		[]PackageChange{{"package1", "1.0.0", "1.0.0.1", "Repository: CRAN", "Repository: BioC", "", ""}},
+		[]PackageChange{{"package3", "3.1.0", "3.0.5", "Repository: CRAN", "Repository: CRAN", "", ""}},
+		[]PackageChange{{"package1", "1.0.0", "1.0.0.1", "Repository: CRAN", "Repository: BioC", "", ""}},
+		[]PackageChange{{
+			"package4", "0.1.0", "0.1.0", "GitHub: org1/package4@main", "GitHub: org1/package4@main",
+			"aaa111", "bbb222",
+		}},
+	})
+	assert.True(t, CheckIfLockfileDiffEmpty(GetLockfileDiff(oldRenvLock, oldRenvLock)))
+}
+
+func Test_FormatLockfileDiff(t *testing.T) {
+	oldRenvLock, newRenvLock := getDiffTestLockfiles()
+	lockfileDiff := GetLockfileDiff(oldRenvLock, newRenvLock)
+	assert.Equal(t, FormatLockfileDiff(lockfileDiff, diffFormatText), `Added packages:
+  package5 5.0 (Repository: CRAN)
+Removed packages:
+  package2 2.0.0 (Repository: CRAN)
+Upgraded packages:
+  package1: 1.0.0 → 1.0.0.1
+Downgraded packages:
+  package3: 3.1.0 → 3.0.5
+Source changes:
+  package1: Repository: CRAN → Repository: BioC
+Git SHA changes:
+  package4: aaa111 → bbb222
+`)
+	assert.Equal(t, FormatLockfileDiff(lockfileDiff, diffFormatMarkdown), `### Added packages
+
+| Package | Version | Source |
+| --- | --- | --- |
+| package5 | 5.0 | Repository: CRAN |
+
+### Removed packages
+
+| Package | Version | Source |
+| --- | --- | --- |
+| package2 | 2.0.0 | Repository: CRAN |
+
+### Upgraded packages
+
+| Package | Old version | New version |
+| --- | --- | --- |
+| package1 | 1.0.0 | 1.0.0.1 |
+
+### Downgraded packages
+
+| Package | Old version | New version |
+| --- | --- | --- |
+| package3 | 3.1.0 | 3.0.5 |
+
+### Source changes
+
+| Package | Old source | New source |
+| --- | --- | --- |
+| package1 | Repository: CRAN | Repository: BioC |
+
+### Git SHA changes
+
+| Package | Old SHA | New SHA |
+| --- | --- | --- |
+| package4 | aaa111 | bbb222 |
+
+`)
+	assert.Equal(t, FormatLockfileDiff(GetLockfileDiff(oldRenvLock, oldRenvLock), diffFormatJSON), `{
+  "added": [],
+  "removed": [],
+  "upgraded": [],
+  "downgraded": [],
+  "sourceChanged": [],
+  "shaChanged": []
+}
+`)
+	assert.Equal(t, FormatLockfileDiff(GetLockfileDiff(oldRenvLock, oldRenvLock), diffFormatText), "No changes.\n")
+}
+
+func Test_ReportDryRun(t *testing.T) {
+	renvLock := ReadRenvLock("testdata/renv_lossless.lock")
+	outputFileName := t.TempDir() + "/changes.json"
+	assert.False(t, ReportDryRun("testdata/renv_lossless.lock", renvLock, outputFileName))
+	updatedPackage := renvLock.Packages["package2"]
+	updatedPackage.Version = "2.1.0"
+	renvLock.Packages["package2"] = updatedPackage
+	assert.True(t, ReportDryRun("testdata/renv_lossless.lock", renvLock, outputFileName))
+	byteValue, err := os.ReadFile(outputFileName)
+	assert.Nil(t, err)
+	var lockfileDiff LockfileDiff
+	err = json.Unmarshal(byteValue, &lockfileDiff)
+	assert.Nil(t, err)
+	assert.Equal(t, lockfileDiff.Upgraded, []PackageChange{
+		{"package2", "2.0.0", "2.1.0", "Repository: CRAN", "Repository: CRAN", "", ""},
+	})
+}
@@ -77,6 +77,42 @@ func DownloadTextFile(url string, parameters map[string]string) (int64, string,
 	return 0, "", err
 }
 
+// CheckURLExists sends a HEAD request to url and returns an error if the resource is not available.
+func CheckURLExists(url string) error { // #nosec G402
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := &http.Client{Transport: tr}
+	resp, err := client.Head(url) // #nosec G107
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Received status code " + fmt.Sprint(resp.StatusCode))
+	}
+	return nil
+}
+
+// CacheDownloads returns a function which downloads files using downloadFileFunction,
+// but downloads each URL only once. Subsequent calls for the same URL return the cached result.
+func CacheDownloads(downloadFileFunction func(string, map[string]string) (int64, string, error),
+) func(string, map[string]string) (int64, string, error) {
+	type downloadResult struct {
+		length  int64
+		content string
+		err     error
+	}
+	cache := make(map[string]downloadResult)
+	return func(url string, parameters map[string]string) (int64, string, error) {
+		if result, ok := cache[url]; ok {
+			log.Trace("Using cached contents of ", url)
+			return result.length, result.content, result.err
+		}
+		length, content, err := downloadFileFunction(url, parameters)
+		cache[url] = downloadResult{length, content, err}
+		return length, content, err
+	}
+}
+
 // GetGitLabProjectAndSha retrieves information about GitLab repository
 // (project path, repository name and commit SHA)
 // from projectURL GitLab API endpoint.
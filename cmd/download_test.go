@@ -314,3 +314,20 @@ func Test_GetPackagesFileContent(t *testing.T) {
 	assert.Equal(t, packagesContentSrc, "PACKAGES content src/contrib")
 	assert.Equal(t, packagesContent, "")
 }
+
+func Test_CacheDownloads(t *testing.T) {
+	var downloadCount int
+	cachedDownloadTextFile := CacheDownloads(func(url string, parameters map[string]string) (int64, string, error) {
+		downloadCount++
+		return mockedDownloadTextFile(url, parameters)
+	})
+	for i := 0; i < 3; i++ {
+		_, content, err := cachedDownloadTextFile(
+			"https://gitlab.example.com/api/v4/projects/30176/repository/files/DESCRIPTION/raw?ref=v0.2.0",
+			map[string]string{},
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, content, "DESCRIPTION contents 3")
+	}
+	assert.Equal(t, downloadCount, 1)
+}
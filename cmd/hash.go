@@ -0,0 +1,98 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/md5" // #nosec G501
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DESCRIPTION fields (apart from Package, Version and Remote* fields) used by renv
+// to calculate the package hash, in the order in which renv uses them.
+var hashFields = []string{
+	"Title", "Author", "Maintainer", "Description", "Depends", "Imports", "Suggests", "LinkingTo",
+}
+
+// CheckIfRemoteField checks whether the DESCRIPTION field is one of the Remote* fields describing
+// the remote source of the package. The "Remotes" field (listing remote dependencies) is not one of them.
+func CheckIfRemoteField(fieldName string) bool {
+	return strings.HasPrefix(fieldName, "Remote") && !strings.HasPrefix(fieldName, "Remotes")
+}
+
+// GetPackageRemoteFields returns a map with the Remote* fields of the package, both the ones
+// stored in the PackageDescription struct and the ones retained from PACKAGES or DESCRIPTION file.
+func GetPackageRemoteFields(p PackageDescription) map[string]string {
+	remoteFields := make(map[string]string)
+	for k, v := range p.Fields {
+		if CheckIfRemoteField(k) {
+			remoteFields[k] = v
+		}
+	}
+	for k, v := range map[string]string{
+		"RemoteType": p.RemoteType, "RemoteHost": p.RemoteHost, "RemoteUsername": p.RemoteUsername,
+		"RemoteRepo": p.RemoteRepo, "RemoteSubdir": p.RemoteSubdir, "RemoteRef": p.RemoteRef,
+		"RemoteSha": p.RemoteSha,
+	} {
+		if v != "" {
+			remoteFields[k] = v
+		}
+	}
+	return remoteFields
+}
+
+// GetPackageHash calculates the package hash in the same way as renv does it. The hash is the MD5 sum
+// of the Package, Version, hashFields and Remote* fields (sorted by name) with all whitespace removed,
+// formatted as 'Field: value' lines. Remote* fields are only used for packages from git repositories
+// locked to a full git commit SHA, and RemoteRef is skipped if it's 'HEAD'.
+// In case of packages from package repositories, an empty string is returned, as the PACKAGES files
+// don't contain all hashed DESCRIPTION fields (e.g. Title or Author), so the hash wouldn't match the one
+// calculated by renv.
+func GetPackageHash(p PackageDescription) string {
+	if p.Source != GitHub && p.Source != GitLab {
+		return ""
+	}
+	fieldNames := []string{"Package", "Version"}
+	fieldValues := map[string]string{"Package": p.Package, "Version": p.Version}
+	for _, f := range hashFields {
+		if v, ok := p.Fields[f]; ok {
+			fieldNames = append(fieldNames, f)
+			fieldValues[f] = v
+		}
+	}
+	remoteFields := GetPackageRemoteFields(p)
+	if len(remoteFields["RemoteSha"]) >= 40 {
+		var remoteFieldNames []string
+		for k := range remoteFields {
+			if k == "RemoteRef" && remoteFields[k] == "HEAD" {
+				continue
+			}
+			remoteFieldNames = append(remoteFieldNames, k)
+			fieldValues[k] = remoteFields[k]
+		}
+		sort.Strings(remoteFieldNames)
+		fieldNames = append(fieldNames, remoteFieldNames...)
+	}
+	var hashContents string
+	for _, f := range fieldNames {
+		hashContents += f + ": " + strings.Join(strings.FieldsFunc(fieldValues[f], unicode.IsSpace), "") + "\n"
+	}
+	sum := md5.Sum([]byte(hashContents)) // #nosec G401
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckIfRemoteField(t *testing.T) {
+	assert.True(t, CheckIfRemoteField("RemoteSha"))
+	assert.True(t, CheckIfRemoteField("RemoteUrl"))
+	assert.False(t, CheckIfRemoteField("Remotes"))
+	assert.False(t, CheckIfRemoteField("Repository"))
+}
+
+func Test_GetPackageHash(t *testing.T) {
+	// Package from GitLab.
+	hash1 := GetPackageHash(PackageDescription{
+		"pkgA", "1.2.3", "GitLab", "", []Dependency{},
+		"gitlab", "https://gitlab.example.com", "group1", "pkgA", "", "main", "0123456", []string{}, "",
+		map[string]string{
+			"Title":   "A title",
+			"Depends": "R (>= 4.0),",
+			"Imports": "dplyr,\ntidyr (>= 1.0.0)",
+			"OS_type": "unix",
+		},
+	})
+	// MD5 sum of: "Package: pkgA\nVersion: 1.2.3\nTitle: Atitle\nDepends: R(>=4.0),\nImports: dplyr,tidyr(>=1.0.0)\n"
+	assert.Equal(t, hash1, "90382a67b726926508d2313f818ed1b3")
+	// Package from GitHub with RemoteRef: HEAD which should be skipped.
+	hash2 := GetPackageHash(PackageDescription{
+		"pkgB", "0.1.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "pkgB", "", "HEAD", "0123456789abcdef0123456789abcdef01234567",
+		[]string{}, "", map[string]string{"Title": "B title", "Remotes": "org1/pkgD"},
+	})
+	// MD5 sum of: "Package: pkgB\nVersion: 0.1.0\nTitle: Btitle\nRemoteHost: api.github.com\nRemoteRepo: pkgB\n" +
+	// "RemoteSha: 0123456789abcdef0123456789abcdef01234567\nRemoteType: github\nRemoteUsername: org1\n"
+	assert.Equal(t, hash2, "0d06a57cd2cbb47c820a48d7f2ae68bf")
+	// Package from a CRAN-like repository for which the hash can't be calculated from PACKAGES file.
+	hash3 := GetPackageHash(PackageDescription{
+		"pkgC", "2.0", "Repository", "CRAN", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "",
+		map[string]string{"Title": "C title", "RemoteType": "standard", "RemoteSha": "2.0"},
+	})
+	assert.Equal(t, hash3, "")
+	// Package from GitHub locked to a short SHA for which Remote* fields are skipped.
+	// MD5 sum of: "Package: pkgC\nVersion: 2.0\n"
+	hash4 := GetPackageHash(PackageDescription{
+		"pkgC", "2.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "pkgC", "", "main", "0123456", []string{}, "", nil,
+	})
+	assert.Equal(t, hash4, "ed6153f345068a07dc487d16da294eae")
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// normalizeLicense removes the whitespace from the license name and converts it to lowercase,
+// so that e.g. 'GPL (>= 2)' and 'GPL(>=2)' are treated as the same license.
+func normalizeLicense(license string) string {
+	return strings.ToLower(strings.Join(strings.Fields(license), ""))
+}
+
+// LicenseMatches checks whether the license (a single alternative from the License field, or a part of it)
+// matches any of the license policy entries. The entries are matched against the license name and against
+// its SPDX identifier, and can contain '*' wildcards.
+func LicenseMatches(license string, policyEntries []string) bool {
+	candidates := []string{normalizeLicense(license)}
+	if spdxLicense := GetSPDXLicense(license); spdxLicense != "" {
+		candidates = append(candidates, normalizeLicense(spdxLicense))
+	}
+	for _, entry := range policyEntries {
+		entryRegexp := strings.ReplaceAll(regexp.QuoteMeta(normalizeLicense(entry)), `\*`, ".*")
+		re := regexp.MustCompile("^" + entryRegexp + "$")
+		for _, c := range candidates {
+			if re.MatchString(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckLicense checks whether the License field of a package is acceptable under the licensePolicy.
+// Alternative licenses are separated with '|', and the package is acceptable if at least one
+// alternative is acceptable. An alternative (e.g. 'MIT + file LICENSE') is acceptable if it's not
+// denied (as a whole or any of its '+'-separated parts), and if it's allowed (as a whole or all of its parts)
+// in case the list of allowed licenses is not empty.
+func CheckLicense(license string, licensePolicy LicensePolicy) bool {
+	if strings.TrimSpace(license) == "" {
+		return len(licensePolicy.Allowed) == 0
+	}
+	for _, alternative := range strings.Split(license, "|") {
+		parts := strings.Split(alternative, "+")
+		denied := LicenseMatches(alternative, licensePolicy.Denied)
+		allowed := len(licensePolicy.Allowed) == 0 || LicenseMatches(alternative, licensePolicy.Allowed)
+		allPartsAllowed := true
+		for _, part := range parts {
+			if LicenseMatches(part, licensePolicy.Denied) {
+				denied = true
+			}
+			if !LicenseMatches(part, licensePolicy.Allowed) {
+				allPartsAllowed = false
+			}
+		}
+		if !denied && (allowed || allPartsAllowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDependencyChain returns the shortest chain of dependencies (based on the Requirements of packages
+// in renvLock) through which the packageName is required by any of the directPackages, e.g.
+// ['teal', 'shiny', 'httpuv']. If packageName isn't reachable from directPackages, the returned
+// chain contains only packageName.
+func GetDependencyChain(renvLock RenvLock, directPackages []string, packageName string) []string {
+	previous := make(map[string]string)
+	var queue []string
+	for _, p := range directPackages {
+		if _, ok := renvLock.Packages[p]; ok {
+			if _, visited := previous[p]; !visited {
+				previous[p] = ""
+				queue = append(queue, p)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == packageName {
+			var chain []string
+			for p := current; p != ""; p = previous[p] {
+				chain = append([]string{p}, chain...)
+			}
+			return chain
+		}
+		for _, r := range renvLock.Packages[current].Requirements {
+			if _, visited := previous[r]; !visited {
+				previous[r] = current
+				queue = append(queue, r)
+			}
+		}
+	}
+	return []string{packageName}
+}
+
+// CheckLicensePolicy returns the list of packages in renvLock which violate the licensePolicy,
+// together with their licenses and the dependency chains through which they're required by the input packages.
+func CheckLicensePolicy(renvLock RenvLock, inputPackages []PackageDescription, licensePolicy LicensePolicy) []string {
+	var violations []string
+	if len(licensePolicy.Allowed) == 0 && len(licensePolicy.Denied) == 0 {
+		return violations
+	}
+	var directPackages, packageNames []string
+	for _, p := range inputPackages {
+		directPackages = append(directPackages, p.Package)
+	}
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	for _, k := range packageNames {
+		license := renvLock.Packages[k].Fields["License"]
+		if CheckLicense(license, licensePolicy) {
+			continue
+		}
+		if license == "" {
+			license = "unknown"
+		}
+		violations = append(violations, k+" (License: "+strings.ReplaceAll(license, "\n", " ")+
+			"), required by: "+strings.Join(GetDependencyChain(renvLock, directPackages, k), " -> "))
+	}
+	return violations
+}
+
+// ReportLicenseViolations prints the packages violating the license policy in all targets,
+// and exits with a non-zero exit code if there are any.
+func ReportLicenseViolations(violations []string) {
+	if len(violations) == 0 {
+		return
+	}
+	fmt.Println("Packages violating the license policy:")
+	for _, v := range violations {
+		fmt.Println("  " + v)
+	}
+	os.Exit(problemsFoundExitCode)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LicenseMatches(t *testing.T) {
+	assert.True(t, LicenseMatches("GPL-3", []string{"GPL-3"}))
+	assert.True(t, LicenseMatches("GPL (>= 2)", []string{"gpl(>=2)"}))
+	assert.True(t, LicenseMatches("GPL (>= 2)", []string{"GPL-2.0-or-later"}))
+	assert.True(t, LicenseMatches("AGPL-3", []string{"MIT", "AGPL*"}))
+	assert.True(t, LicenseMatches(" file LICENSE", []string{"file LICENSE"}))
+	assert.False(t, LicenseMatches("LGPL-3", []string{"GPL*"}))
+	assert.False(t, LicenseMatches("MIT", []string{}))
+}
+
+func Test_CheckLicense(t *testing.T) {
+	allowed := LicensePolicy{[]string{"MIT", "GPL*", "Apache-2.0"}, []string{}}
+	assert.True(t, CheckLicense("MIT", allowed))
+	assert.True(t, CheckLicense("Apache License (== 2.0)", allowed))
+	assert.True(t, CheckLicense("LGPL-3 | GPL-3", allowed))
+	assert.False(t, CheckLicense("LGPL-3", allowed))
+	assert.False(t, CheckLicense("", allowed))
+	// The SPDX identifier of 'MIT + file LICENSE' is 'MIT'.
+	assert.True(t, CheckLicense("MIT + file LICENSE", allowed))
+	assert.False(t, CheckLicense("file LICENSE", allowed))
+	assert.True(t, CheckLicense(
+		"CC BY 4.0 + file LICENSE", LicensePolicy{[]string{"CC-BY-4.0", "file LICENSE"}, []string{}},
+	))
+
+	denied := LicensePolicy{[]string{}, []string{"GPL-3", "file LICENSE"}}
+	assert.True(t, CheckLicense("MIT", denied))
+	assert.True(t, CheckLicense("", denied))
+	assert.True(t, CheckLicense("GPL-2 | GPL-3", denied))
+	assert.False(t, CheckLicense("GPL-3", denied))
+	assert.False(t, CheckLicense("MIT + file LICENSE", denied))
+	assert.False(t, CheckLicense("file LICENSE", denied))
+
+	// Denied licenses take precedence over allowed licenses.
+	assert.False(t, CheckLicense("GPL-3", LicensePolicy{[]string{"GPL*"}, []string{"GPL-3"}}))
+}
+
+func Test_GetDependencyChain(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{}, nil,
+		map[string]PackageDescription{
+			"package1": {Package: "package1", Requirements: []string{"package2", "package4"}},
+			"package2": {Package: "package2", Requirements: []string{"package3"}},
+			"package3": {Package: "package3"},
+			"package4": {Package: "package4", Requirements: []string{"package3"}},
+			"package5": {Package: "package5"},
+		},
+	}
+	assert.Equal(t, GetDependencyChain(renvLock, []string{"package1"}, "package3"),
+		[]string{"package1", "package2", "package3"})
+	assert.Equal(t, GetDependencyChain(renvLock, []string{"package1"}, "package1"), []string{"package1"})
+	assert.Equal(t, GetDependencyChain(renvLock, []string{"package1"}, "package5"), []string{"package5"})
+}
+
+func Test_CheckLicensePolicy(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package2"}, "", map[string]string{"License": "MIT"},
+			},
+			"package2": {
+				"package2", "2.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package3"}, "", map[string]string{"License": "GPL-2 | GPL-3"},
+			},
+			"package3": {
+				"package3", "3.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", map[string]string{"License": "file LICENSE"},
+			},
+			"package4": {
+				"package4", "4.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package4", "", "main", "aaa111", []string{}, "", nil,
+			},
+		},
+	}
+	inputPackages := []PackageDescription{{Package: "package1"}, {Package: "package4"}}
+	assert.Empty(t, CheckLicensePolicy(renvLock, inputPackages, LicensePolicy{}))
+	assert.Equal(t, CheckLicensePolicy(renvLock, inputPackages, LicensePolicy{[]string{}, []string{"file LICENSE"}}),
+		[]string{"package3 (License: file LICENSE), required by: package1 -> package2 -> package3"})
+	assert.Equal(t, CheckLicensePolicy(renvLock, inputPackages, LicensePolicy{[]string{"MIT", "GPL-3"}, []string{}}),
+		[]string{
+			"package3 (License: file LICENSE), required by: package1 -> package2 -> package3",
+			"package4 (License: unknown), required by: package4",
+		})
+}
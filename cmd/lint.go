@@ -0,0 +1,192 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Remote* fields required in the records of packages from git repositories.
+var requiredGitRecordFields = []string{
+	"RemoteType", "RemoteHost", "RemoteUsername", "RemoteRepo", "RemoteRef", "RemoteSha",
+}
+
+func newLintCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint <renv.lock>",
+		Short: "Check the structure and internal consistency of renv.lock",
+		Long: `Check the structure and internal consistency of renv.lock: whether the repositories
+referenced by the packages are defined in the lockfile header, whether the records of
+packages from git repositories contain all Remote* fields, whether there are no duplicate
+or blank records, and whether the dependencies of all packages are satisfied by the
+package versions in the lockfile.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			setLogLevel()
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				log.Fatal("Could not read lockfile ", args[0], ": ", err)
+			}
+			ReportProblems(LintRenvLock(content), args[0])
+		},
+	}
+}
+
+// GetRecordDependencies returns the hard dependencies (Depends, Imports, LinkingTo) saved in the lockfile
+// record, together with the version constraints. If the record doesn't list them (e.g. in the legacy
+// lockfile format), the dependencies are taken from the Requirements field, without version constraints.
+func GetRecordDependencies(record LockfileRecord, p PackageDescription) []Dependency {
+	fields := make(map[string]string)
+	for _, fieldName := range []string{depends, imports, linkingTo} {
+		value, ok := record.Get(fieldName)
+		if !ok {
+			continue
+		}
+		var items []string
+		if err := json.Unmarshal(value.(json.RawMessage), &items); err != nil {
+			// The field may also be saved as a single comma-separated string.
+			var item string
+			if err := json.Unmarshal(value.(json.RawMessage), &item); err == nil {
+				items = []string{item}
+			}
+		}
+		fields[fieldName] = strings.Join(items, ", ")
+	}
+	if len(fields) > 0 {
+		return GetDependenciesFromFields(fields)
+	}
+	var dependencies []Dependency
+	for _, r := range p.Requirements {
+		dependencies = append(dependencies, Dependency{imports, r, "", ""})
+	}
+	return dependencies
+}
+
+// LintRecord checks a single package record saved under the name recordName in the lockfile.
+// repositoryNames contains the names of repositories defined in the lockfile header,
+// and packages contains all packages from the lockfile. It returns the list of problems.
+func LintRecord(recordName string, record LockfileRecord, repositoryNames []string,
+	packages map[string]PackageDescription) []string {
+	var problems []string
+	var p PackageDescription
+	recordJSON, err := json.Marshal(record)
+	checkError(err)
+	if err := json.Unmarshal(recordJSON, &p); err != nil {
+		return []string{recordName + ": incorrect record: " + err.Error()}
+	}
+	if p.Package == "" || p.Version == "" || p.Source == "" {
+		return []string{recordName + ": blank record (Package, Version or Source missing)"}
+	}
+	if p.Package != recordName {
+		problems = append(problems, recordName+": record contains package "+p.Package)
+	}
+	switch p.Source {
+	case GitHub, GitLab:
+		problems = append(problems, LintGitRecord(recordName, record)...)
+	case "Repository":
+		problems = append(problems, LintRepositoryRecord(recordName, p, repositoryNames)...)
+	}
+	return append(problems, LintRecordDependencies(recordName, record, p, packages)...)
+}
+
+// LintGitRecord checks whether the record of the package from a git repository, saved under the name
+// recordName, contains all required Remote* fields. It returns the list of problems.
+func LintGitRecord(recordName string, record LockfileRecord) []string {
+	var problems []string
+	for _, fieldName := range requiredGitRecordFields {
+		if value, ok := record.Get(fieldName); !ok || string(value.(json.RawMessage)) == `""` {
+			problems = append(problems, recordName+": "+fieldName+" missing")
+		}
+	}
+	return problems
+}
+
+// LintRepositoryRecord checks whether the repository of the package p, saved under the name recordName,
+// is one of repositoryNames defined in the lockfile header. It returns the list of problems.
+func LintRepositoryRecord(recordName string, p PackageDescription, repositoryNames []string) []string {
+	if !stringInSlice(p.Repository, repositoryNames) {
+		return []string{recordName + ": repository " + p.Repository + " has not been defined in the lockfile"}
+	}
+	return nil
+}
+
+// LintRecordDependencies checks whether all hard dependencies of the package p, saved under the name
+// recordName, are satisfied by the packages in the lockfile. It returns the list of problems.
+func LintRecordDependencies(recordName string, record LockfileRecord, p PackageDescription,
+	packages map[string]PackageDescription) []string {
+	var problems []string
+	for _, d := range GetRecordDependencies(record, p) {
+		if d.DependencyType == suggests || CheckIfBasePackage(d.DependencyName) {
+			continue
+		}
+		dependency, ok := packages[d.DependencyName]
+		switch {
+		case !ok && !CheckIfRecommendedPackage(d.DependencyName):
+			problems = append(problems, recordName+": dependency "+d.DependencyName+" missing")
+		case ok && !CheckIfVersionSufficient(dependency.Version, d.VersionOperator, d.VersionValue):
+			problems = append(problems, recordName+": requires "+d.DependencyName+" "+d.VersionOperator+" "+
+				d.VersionValue+" but version "+dependency.Version+" is locked")
+		}
+	}
+	return problems
+}
+
+// LintRenvLock checks the structure and internal consistency of the lockfile content.
+// It returns the list of problems, in the order of packages in the lockfile.
+func LintRenvLock(content []byte) []string {
+	problems := []string{}
+	var renvLock RenvLock
+	if err := json.Unmarshal(content, &renvLock); err != nil {
+		return []string{"incorrect lockfile: " + err.Error()}
+	}
+	var repositoryNames []string
+	for _, r := range renvLock.R.Repositories {
+		if stringInSlice(r.Name, repositoryNames) {
+			problems = append(problems, "repository "+r.Name+" has been defined more than once")
+		}
+		repositoryNames = append(repositoryNames, r.Name)
+	}
+	// The lockfile is read again preserving the order of packages, as the RenvLock
+	// struct doesn't retain duplicate package records.
+	var lockfile, packageRecords LockfileRecord
+	err := json.Unmarshal(content, &lockfile)
+	checkError(err)
+	if packagesJSON, ok := lockfile.Get("Packages"); ok {
+		if err := json.Unmarshal(packagesJSON.(json.RawMessage), &packageRecords); err != nil {
+			return append(problems, "incorrect Packages section: "+err.Error())
+		}
+	}
+	seenRecords := make(map[string]bool)
+	for _, f := range packageRecords {
+		if seenRecords[f.Name] {
+			problems = append(problems, f.Name+": duplicate record")
+			continue
+		}
+		seenRecords[f.Name] = true
+		var record LockfileRecord
+		if err := json.Unmarshal(f.Value.(json.RawMessage), &record); err != nil {
+			problems = append(problems, f.Name+": incorrect record: "+err.Error())
+			continue
+		}
+		problems = append(problems, LintRecord(f.Name, record, repositoryNames, renvLock.Packages)...)
+	}
+	return problems
+}
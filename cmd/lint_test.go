@@ -0,0 +1,84 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LintRenvLock(t *testing.T) {
+	content := []byte(`{
+  "R": {
+    "Version": "4.3.2",
+    "Repositories": [{"Name": "CRAN", "URL": "https://cloud.r-project.org"}]
+  },
+  "Packages": {
+    "package1": {
+      "Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN",
+      "Imports": ["package2 (>= 2.1.0)", "package3", "Matrix", "utils"],
+      "Suggests": ["package9"]
+    },
+    "package2": {
+      "Package": "package2", "Version": "2.0.0", "Source": "Repository", "Repository": "RSPM",
+      "Requirements": ["package4"]
+    },
+    "package2": {
+      "Package": "package2", "Version": "2.0.0", "Source": "Repository", "Repository": "CRAN"
+    },
+    "package4": {
+      "Package": "package4", "Version": "4.0.0", "Source": "GitHub",
+      "RemoteType": "github", "RemoteHost": "api.github.com", "RemoteUsername": "org1",
+      "RemoteRepo": "package4", "RemoteRef": "", "Requirements": ["package2"]
+    },
+    "package5": {
+      "Package": "", "Version": "", "Source": ""
+    },
+    "package6": {
+      "Package": "package7", "Version": "7.0.0", "Source": "Repository", "Repository": "CRAN"
+    }
+  }
+}`)
+	assert.Equal(t, LintRenvLock(content), []string{
+		"package1: requires package2 >= 2.1.0 but version 2.0.0 is locked",
+		"package1: dependency package3 missing",
+		"package2: repository RSPM has not been defined in the lockfile",
+		"package2: duplicate record",
+		"package4: RemoteRef missing",
+		"package4: RemoteSha missing",
+		"package5: blank record (Package, Version or Source missing)",
+		"package6: record contains package package7",
+	})
+	renvLockContent, err := os.ReadFile("testdata/renv_lossless.lock")
+	assert.Nil(t, err)
+	assert.Equal(t, LintRenvLock(renvLockContent), []string{})
+}
+
+func Test_LintGitRecord(t *testing.T) {
+	var record LockfileRecord
+	err := json.Unmarshal([]byte(`{
+  "Package": "package1", "Version": "1.0.0", "Source": "GitHub", "RemoteType": "github",
+  "RemoteHost": "api.github.com", "RemoteUsername": "org1", "RemoteRepo": "package1", "RemoteRef": ""
+}`), &record)
+	assert.Nil(t, err)
+	assert.Equal(t, LintGitRecord("package1", record), []string{
+		"package1: RemoteRef missing", "package1: RemoteSha missing",
+	})
+}
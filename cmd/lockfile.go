@@ -0,0 +1,333 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+const lockfileVersionLegacy = "legacy"
+const lockfileVersionFull = "full"
+
+// Fields from PACKAGES files and DESCRIPTION files which are not saved in the full lockfile records,
+// as they describe a particular build or a particular download location of the package.
+var skippedRecordFields = []string{"Archs", "Built", "File", "MD5sum", "Packaged", "Path", "SHA256", "DownloadURL"}
+
+// Fields which are saved in the full lockfile records as lists of strings.
+var listRecordFields = []string{"Depends", "Imports", "Suggests", "LinkingTo", "Enhances"}
+
+// marshalWithoutEscaping works like json.Marshal, but characters such as '>' in version
+// constraints are saved as they are, instead of being escaped.
+func marshalWithoutEscaping(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(v)
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), err
+}
+
+// MarshalJSON saves the lockfile record as a JSON object with the fields in the order
+// in which they are stored in the record.
+func (r LockfileRecord) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, f := range r {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		name, err := marshalWithoutEscaping(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalWithoutEscaping(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON reads a JSON object into the lockfile record, preserving the order of the fields.
+// The values of the fields are stored as json.RawMessage.
+func (r *LockfileRecord) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delimiter, ok := token.(json.Delim); !ok || delimiter != '{' {
+		return errors.New("lockfile record is not a JSON object")
+	}
+	*r = LockfileRecord{}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return err
+		}
+		*r = append(*r, LockfileRecordField{token.(string), value})
+	}
+	return nil
+}
+
+// Get returns the value of the field with the given name, and whether the field exists in the record.
+func (r LockfileRecord) Get(name string) (interface{}, bool) {
+	for _, f := range r {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of the field with the given name, keeping its position in the record.
+// If there's no such field, it's appended at the end of the record.
+func (r *LockfileRecord) Set(name string, value interface{}) {
+	for i, f := range *r {
+		if f.Name == name {
+			(*r)[i].Value = value
+			return
+		}
+	}
+	*r = append(*r, LockfileRecordField{name, value})
+}
+
+// Delete removes the field with the given name from the record, if it exists.
+func (r *LockfileRecord) Delete(name string) {
+	for i, f := range *r {
+		if f.Name == name {
+			*r = append((*r)[:i], (*r)[i+1:]...)
+			return
+		}
+	}
+}
+
+// CheckIfPackageRecordField checks whether the lockfile record field describes the package version,
+// source or dependencies, as opposed to the fields unknown to locksmith which should be preserved.
+func CheckIfPackageRecordField(fieldName string) bool {
+	return stringInSlice(fieldName, []string{
+		"Package", "Version", "Source", "Repository", "Requirements", "Hash",
+	}) || stringInSlice(fieldName, listRecordFields) || CheckIfRemoteField(fieldName)
+}
+
+// ReplacePackageRecordFields returns the record of an updated package, in which the fields describing
+// the package are replaced with the ones from updatedRecord, so that the fields which are not present
+// in updatedRecord (e.g. RemoteSubdir or removed dependencies) are not preserved from the previous version.
+// The other fields are preserved, and the fields from updatedRecord keep their positions in the record.
+func ReplacePackageRecordFields(record LockfileRecord, updatedRecord LockfileRecord) LockfileRecord {
+	outputRecord := LockfileRecord{}
+	for _, f := range record {
+		if value, ok := updatedRecord.Get(f.Name); ok {
+			outputRecord = append(outputRecord, LockfileRecordField{f.Name, value})
+		} else if !CheckIfPackageRecordField(f.Name) {
+			outputRecord = append(outputRecord, f)
+		}
+	}
+	for _, f := range updatedRecord {
+		if _, ok := outputRecord.Get(f.Name); !ok {
+			outputRecord = append(outputRecord, f)
+		}
+	}
+	return outputRecord
+}
+
+// CheckIfPackageChanged checks whether the version or the source of the package has been changed
+// between the inputPackage read from the input lockfile and the updated package.
+func CheckIfPackageChanged(inputPackage PackageDescription, p PackageDescription) bool {
+	return inputPackage.Version != p.Version || inputPackage.RemoteSha != p.RemoteSha ||
+		inputPackage.Source != p.Source || inputPackage.Repository != p.Repository
+}
+
+// SplitListField converts the value of a DESCRIPTION field containing a comma-separated list
+// (such as Imports) into a list of strings, with the whitespace within each item normalized.
+func SplitListField(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.Join(strings.Fields(item), " ")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetLockfileRecord returns the full lockfile record for the package. The record starts with
+// the fields describing the package source, followed by all other fields from the package
+// PACKAGES file entry or DESCRIPTION file in alphabetical order, and ends with Requirements and Hash.
+func GetLockfileRecord(p PackageDescription) LockfileRecord {
+	record := LockfileRecord{{"Package", p.Package}, {"Version", p.Version}, {"Source", p.Source}}
+	for _, f := range []LockfileRecordField{
+		{"Repository", p.Repository}, {"RemoteType", p.RemoteType}, {"RemoteHost", p.RemoteHost},
+		{"RemoteUsername", p.RemoteUsername}, {"RemoteRepo", p.RemoteRepo}, {"RemoteSubdir", p.RemoteSubdir},
+		{"RemoteRef", p.RemoteRef}, {"RemoteSha", p.RemoteSha},
+	} {
+		if f.Value != "" {
+			record = append(record, f)
+		}
+	}
+	var fieldNames []string
+	for k := range p.Fields {
+		if stringInSlice(k, []string{"Package", "Version", "Source", "Repository", "Requirements", "Hash"}) ||
+			stringInSlice(k, skippedRecordFields) {
+			continue
+		}
+		// The Remote* fields describing the package source have been taken from the struct above.
+		if CheckIfRemoteField(k) && p.Source != "Repository" {
+			continue
+		}
+		fieldNames = append(fieldNames, k)
+	}
+	sort.Strings(fieldNames)
+	for _, k := range fieldNames {
+		if stringInSlice(k, listRecordFields) {
+			record = append(record, LockfileRecordField{k, SplitListField(p.Fields[k])})
+		} else {
+			record = append(record, LockfileRecordField{k, p.Fields[k]})
+		}
+	}
+	if len(p.Requirements) > 0 {
+		record = append(record, LockfileRecordField{"Requirements", p.Requirements})
+	}
+	if p.Hash != "" {
+		record = append(record, LockfileRecordField{"Hash", p.Hash})
+	}
+	return record
+}
+
+// GetLegacyLockfileRecord returns the compact lockfile record for the package,
+// containing only the fields describing the package source.
+func GetLegacyLockfileRecord(p PackageDescription) LockfileRecord {
+	var record LockfileRecord
+	packageJSON, err := json.Marshal(p)
+	checkError(err)
+	err = json.Unmarshal(packageJSON, &record)
+	checkError(err)
+	return record
+}
+
+// CheckLockfileVersion exits with an error if lockfileVersion is not supported.
+func CheckLockfileVersion(lockfileVersion string) {
+	if lockfileVersion != lockfileVersionLegacy && lockfileVersion != lockfileVersionFull {
+		log.Fatal("Unknown --lockfileVersion: ", lockfileVersion, ". Please use one of: ",
+			lockfileVersionLegacy, ", ", lockfileVersionFull, ".")
+	}
+}
+
+// GetLockfileContents returns the structure which should be saved as the output renv.lock file,
+// depending on the lockfileVersion: either the legacy renv.lock with compact package records,
+// or the renv.lock with full package records containing all DESCRIPTION fields.
+func GetLockfileContents(renvLock RenvLock, lockfileVersion string) interface{} {
+	CheckLockfileVersion(lockfileVersion)
+	if lockfileVersion == lockfileVersionLegacy {
+		return renvLock
+	}
+	fullRenvLock := FullRenvLock{renvLock.R, renvLock.Bioconductor, make(map[string]LockfileRecord)}
+	for k, p := range renvLock.Packages {
+		fullRenvLock.Packages[k] = GetLockfileRecord(p)
+	}
+	return fullRenvLock
+}
+
+// GetUpdatedLockfileContents returns the structure which should be saved as the output renv.lock file
+// when the lockfile read from inputFileName has been updated to renvLock. The sections of the input
+// lockfile not modelled by locksmith (e.g. 'Python'), as well as the unknown fields of package records,
+// are preserved together with the order of sections, packages and fields. For packages whose version or source
+// has changed, the fields describing the package are replaced rather than merged. Packages which are not present
+// in renvLock are removed, and packages which are not present in the input lockfile are added at the end.
+// The repositories in the header are taken from renvLock, as their URLs may have been changed (e.g. by --snapshotDate).
+func GetUpdatedLockfileContents(inputFileName string, renvLock RenvLock, lockfileVersion string) LockfileRecord {
+	CheckLockfileVersion(lockfileVersion)
+	var inputRenvLock LockfileRecord
+	byteValue, err := os.ReadFile(inputFileName)
+	checkError(err)
+	err = json.Unmarshal(byteValue, &inputRenvLock)
+	checkError(err)
+	if rJSON, ok := inputRenvLock.Get("R"); ok {
+		var rRecord LockfileRecord
+		err = json.Unmarshal(rJSON.(json.RawMessage), &rRecord)
+		checkError(err)
+		if _, ok := rRecord.Get("Repositories"); ok || len(renvLock.R.Repositories) > 0 {
+			rRecord.Set("Repositories", renvLock.R.Repositories)
+		}
+		inputRenvLock.Set("R", rRecord)
+	}
+	var inputPackages LockfileRecord
+	if packagesJSON, ok := inputRenvLock.Get("Packages"); ok {
+		err = json.Unmarshal(packagesJSON.(json.RawMessage), &inputPackages)
+		checkError(err)
+	}
+	inputRenvLockPackages := ReadRenvLock(inputFileName).Packages
+	outputPackages := LockfileRecord{}
+	processedPackages := make(map[string]bool)
+	for _, inputPackage := range inputPackages {
+		p, ok := renvLock.Packages[inputPackage.Name]
+		if !ok {
+			continue
+		}
+		var record LockfileRecord
+		err = json.Unmarshal(inputPackage.Value.(json.RawMessage), &record)
+		checkError(err)
+		var updatedRecord LockfileRecord
+		if lockfileVersion == lockfileVersionFull {
+			updatedRecord = GetLockfileRecord(p)
+		} else {
+			updatedRecord = GetLegacyLockfileRecord(p)
+		}
+		if CheckIfPackageChanged(inputRenvLockPackages[inputPackage.Name], p) {
+			record = ReplacePackageRecordFields(record, updatedRecord)
+		} else {
+			for _, f := range updatedRecord {
+				record.Set(f.Name, f.Value)
+			}
+			// Requirements are omitted from the updated record if they have all been removed.
+			if _, ok := updatedRecord.Get("Requirements"); !ok {
+				record.Delete("Requirements")
+			}
+		}
+		outputPackages = append(outputPackages, LockfileRecordField{inputPackage.Name, record})
+		processedPackages[inputPackage.Name] = true
+	}
+	var newPackageNames []string
+	for k := range renvLock.Packages {
+		if !processedPackages[k] {
+			newPackageNames = append(newPackageNames, k)
+		}
+	}
+	sort.Strings(newPackageNames)
+	for _, k := range newPackageNames {
+		if lockfileVersion == lockfileVersionFull {
+			outputPackages.Set(k, GetLockfileRecord(renvLock.Packages[k]))
+		} else {
+			outputPackages.Set(k, GetLegacyLockfileRecord(renvLock.Packages[k]))
+		}
+	}
+	inputRenvLock.Set("Packages", outputPackages)
+	return inputRenvLock
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SplitListField(t *testing.T) {
+	assert.Equal(t, SplitListField("R (>= 3.6),\nrtables (>\n0.6.4),"), []string{"R (>= 3.6)", "rtables (> 0.6.4)"})
+	assert.Equal(t, SplitListField(""), []string{})
+}
+
+func Test_GetLockfileRecord(t *testing.T) {
+	record1 := GetLockfileRecord(PackageDescription{
+		"somePackage1", "1.0.0", "Repository", "CRAN", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "abc123",
+		map[string]string{
+			"Package":          "somePackage1",
+			"Version":          "1.0.0",
+			"Depends":          "R (>= 2.15.0)",
+			"License":          "GPL (>= 2)",
+			"MD5sum":           "aaa333444555666777",
+			"NeedsCompilation": "no",
+		},
+	})
+	assert.Equal(t, record1, LockfileRecord{
+		{"Package", "somePackage1"},
+		{"Version", "1.0.0"},
+		{"Source", "Repository"},
+		{"Repository", "CRAN"},
+		{"Depends", []string{"R (>= 2.15.0)"}},
+		{"License", "GPL (>= 2)"},
+		{"NeedsCompilation", "no"},
+		{"Hash", "abc123"},
+	})
+	record2 := GetLockfileRecord(PackageDescription{
+		"somePackage2", "0.1.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "somePackage2", "", "main", "aaabbb444333",
+		[]string{"R6"}, "",
+		map[string]string{
+			"Package":    "somePackage2",
+			"Title":      "Some Title",
+			"RemoteType": "local",
+			"Imports":    "R6",
+		},
+	})
+	assert.Equal(t, record2, LockfileRecord{
+		{"Package", "somePackage2"},
+		{"Version", "0.1.0"},
+		{"Source", "GitHub"},
+		{"RemoteType", "github"},
+		{"RemoteHost", "api.github.com"},
+		{"RemoteUsername", "org1"},
+		{"RemoteRepo", "somePackage2"},
+		{"RemoteRef", "main"},
+		{"RemoteSha", "aaabbb444333"},
+		{"Imports", []string{"R6"}},
+		{"Title", "Some Title"},
+		{"Requirements", []string{"R6"}},
+	})
+	recordJSON, err := marshalWithoutEscaping(record1)
+	assert.Nil(t, err)
+	assert.Equal(t, string(recordJSON),
+		`{"Package":"somePackage1","Version":"1.0.0","Source":"Repository","Repository":"CRAN",`+
+			`"Depends":["R (>= 2.15.0)"],"License":"GPL (>= 2)","NeedsCompilation":"no","Hash":"abc123"}`)
+}
+
+func Test_GetLockfileContents(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"4.4", []RenvLockRepository{{"CRAN", "https://cloud.r-project.org"}}},
+		&RenvLockBioconductor{"3.18"},
+		map[string]PackageDescription{
+			"somePackage1": {
+				"somePackage1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "",
+				map[string]string{"License": "GPL-3"},
+			},
+		},
+	}
+	assert.Equal(t, GetLockfileContents(renvLock, lockfileVersionLegacy), renvLock)
+	assert.Equal(t, GetLockfileContents(renvLock, lockfileVersionFull), FullRenvLock{
+		RenvLockContents{"4.4", []RenvLockRepository{{"CRAN", "https://cloud.r-project.org"}}},
+		&RenvLockBioconductor{"3.18"},
+		map[string]LockfileRecord{
+			"somePackage1": {
+				{"Package", "somePackage1"},
+				{"Version", "1.0.0"},
+				{"Source", "Repository"},
+				{"Repository", "CRAN"},
+				{"License", "GPL-3"},
+			},
+		},
+	})
+}
+
+func Test_LockfileRecordGetSet(t *testing.T) {
+	var record LockfileRecord
+	err := json.Unmarshal([]byte(`{"b": "2", "a": ["1"]}`), &record)
+	assert.Nil(t, err)
+	record.Set("a", "3")
+	record.Set("c", "4")
+	value, ok := record.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, value, "3")
+	_, ok = record.Get("d")
+	assert.False(t, ok)
+	recordJSON, err := json.Marshal(record)
+	assert.Nil(t, err)
+	assert.Equal(t, string(recordJSON), `{"b":"2","a":"3","c":"4"}`)
+}
+
+func Test_GetUpdatedLockfileContents(t *testing.T) {
+	var renvLock RenvLock
+	byteValue, err := os.ReadFile("testdata/renv_lossless.lock")
+	assert.Nil(t, err)
+	err = json.Unmarshal(byteValue, &renvLock)
+	assert.Nil(t, err)
+	updatedPackage := renvLock.Packages["package1"]
+	updatedPackage.Version = "1.1.0"
+	updatedPackage.Hash = "ccc333"
+	renvLock.Packages["package1"] = updatedPackage
+	renvLock.Packages["package3"] = PackageDescription{
+		"package3", "3.0.0", "Repository", "CRAN", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "ddd444", nil,
+	}
+	outputJSON, err := json.MarshalIndent(
+		GetUpdatedLockfileContents("testdata/renv_lossless.lock", renvLock, lockfileVersionLegacy), "", "  ",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, string(outputJSON), `{
+  "R": {
+    "Version": "4.3.2",
+    "Repositories": [
+      {
+        "Name": "CRAN",
+        "URL": "https://cloud.r-project.org"
+      }
+    ]
+  },
+  "Bioconductor": {
+    "Version": "3.18"
+  },
+  "Python": {
+    "Version": "3.11.4",
+    "Type": "virtualenv",
+    "Name": "./renv/python/virtualenvs/renv-python-3.11"
+  },
+  "Packages": {
+    "package2": {
+      "Package": "package2",
+      "Version": "2.0.0",
+      "Source": "Repository",
+      "Repository": "CRAN",
+      "Requirements": [
+        "R",
+        "utils"
+      ],
+      "Hash": "aaa111"
+    },
+    "package1": {
+      "Package": "package1",
+      "Version": "1.1.0",
+      "Source": "Repository",
+      "Repository": "CRAN",
+      "Title": "Package with unknown fields",
+      "Hash": "ccc333",
+      "Config/Needs/website": "pkgdown"
+    },
+    "package3": {
+      "Package": "package3",
+      "Version": "3.0.0",
+      "Source": "Repository",
+      "Repository": "CRAN",
+      "Hash": "ddd444"
+    }
+  }
+}`)
+}
+
+func Test_GetUpdatedLockfileContentsFull(t *testing.T) {
+	inputFileName := t.TempDir() + "/renv.lock"
+	err := os.WriteFile(inputFileName, []byte(`{
+  "R": {"Version": "4.3.2", "Repositories": []},
+  "Packages": {
+    "package1": {
+      "Package": "package1",
+      "Version": "1.0.0",
+      "Source": "GitHub",
+      "RemoteType": "github",
+      "RemoteHost": "api.github.com",
+      "RemoteUsername": "org1",
+      "RemoteRepo": "package1",
+      "RemoteSubdir": "pkg",
+      "RemoteRef": "main",
+      "RemoteSha": "aaa111",
+      "Config/Needs/website": "pkgdown",
+      "Imports": ["dplyr", "tidyr"],
+      "Requirements": ["dplyr", "tidyr"],
+      "Hash": "bbb222"
+    },
+    "package2": {
+      "Package": "package2",
+      "Version": "2.0.0",
+      "Source": "Repository",
+      "Repository": "CRAN",
+      "Requirements": ["R", "tidyr"],
+      "Hash": "ccc333"
+    }
+  }
+}`), 0600)
+	assert.Nil(t, err)
+	renvLock := RenvLock{
+		RenvLockContents{"4.3.2", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			// The package has been moved to the root of the repository, and no longer imports tidyr.
+			"package1": {
+				"package1", "1.1.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package1", "", "main", "ddd444", []string{"dplyr"}, "eee555",
+				map[string]string{"Imports": "dplyr"},
+			},
+			// All requirements of the package have been removed.
+			"package2": {
+				"package2", "2.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "ccc333", nil,
+			},
+		},
+	}
+	outputJSON, err := json.MarshalIndent(
+		GetUpdatedLockfileContents(inputFileName, renvLock, lockfileVersionFull), "", "  ",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, string(outputJSON), `{
+  "R": {
+    "Version": "4.3.2",
+    "Repositories": []
+  },
+  "Packages": {
+    "package1": {
+      "Package": "package1",
+      "Version": "1.1.0",
+      "Source": "GitHub",
+      "RemoteType": "github",
+      "RemoteHost": "api.github.com",
+      "RemoteUsername": "org1",
+      "RemoteRepo": "package1",
+      "RemoteRef": "main",
+      "RemoteSha": "ddd444",
+      "Config/Needs/website": "pkgdown",
+      "Imports": [
+        "dplyr"
+      ],
+      "Requirements": [
+        "dplyr"
+      ],
+      "Hash": "eee555"
+    },
+    "package2": {
+      "Package": "package2",
+      "Version": "2.0.0",
+      "Source": "Repository",
+      "Repository": "CRAN",
+      "Hash": "ccc333"
+    }
+  }
+}`)
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newMergeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge <renv.lock> <renv.lock> [<renv.lock>...]",
+		Short: "Merge multiple renv.lock files into one",
+		Long: `Merge multiple renv.lock files into one lockfile saved to --outputRenvLock.
+The lockfile contains all packages from the input lockfiles, and the repositories from
+all input lockfiles (repositories with the same name but different URLs are renamed).
+If a package is locked in different versions, the highest version satisfying the version
+constraints of the selected packages is used. Conflicts which can't be reconciled are reported.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			setLogLevel()
+			var renvLocks []RenvLock
+			var records []map[string]LockfileRecord
+			for _, fileName := range args {
+				content, err := os.ReadFile(fileName)
+				if err != nil {
+					log.Fatal("Could not read lockfile ", fileName, ": ", err)
+				}
+				renvLocks = append(renvLocks, ReadRenvLock(fileName))
+				records = append(records, GetLockfileRecords(content))
+			}
+			mergedRenvLock, conflicts := MergeRenvLocks(renvLocks, records, args)
+			writeJSON(outputRenvLock, mergedRenvLock)
+			ReportProblems(conflicts, outputRenvLock)
+		},
+	}
+}
+
+// GetLockfileRecords returns the package records from the lockfile content, preserving all their fields.
+func GetLockfileRecords(content []byte) map[string]LockfileRecord {
+	var lockfile, packageRecords LockfileRecord
+	err := json.Unmarshal(content, &lockfile)
+	checkError(err)
+	if packagesJSON, ok := lockfile.Get("Packages"); ok {
+		err = json.Unmarshal(packagesJSON.(json.RawMessage), &packageRecords)
+		checkError(err)
+	}
+	records := make(map[string]LockfileRecord)
+	for _, f := range packageRecords {
+		var record LockfileRecord
+		err = json.Unmarshal(f.Value.(json.RawMessage), &record)
+		checkError(err)
+		records[f.Name] = record
+	}
+	return records
+}
+
+// MergeRepositories returns the list of repositories from all renvLocks, and for each of renvLocks,
+// the map from the original repository names to the names in the merged list. Repositories with the
+// same URL are merged, while repositories with the same name but different URLs are renamed
+// by adding a numeric suffix, e.g. 'CRAN_2'.
+func MergeRepositories(renvLocks []RenvLock) ([]RenvLockRepository, []map[string]string) {
+	mergedRepositories := []RenvLockRepository{}
+	var repositoryNames []map[string]string
+	for _, renvLock := range renvLocks {
+		names := make(map[string]string)
+		for _, r := range renvLock.R.Repositories {
+			var mergedName string
+			var nameTaken bool
+			for _, m := range mergedRepositories {
+				if m.URL == r.URL {
+					mergedName = m.Name
+				}
+				if m.Name == r.Name {
+					nameTaken = true
+				}
+			}
+			if mergedName == "" {
+				mergedName = r.Name
+				for i := 2; nameTaken; i++ {
+					mergedName = r.Name + "_" + fmt.Sprint(i)
+					nameTaken = false
+					for _, m := range mergedRepositories {
+						if m.Name == mergedName {
+							nameTaken = true
+						}
+					}
+				}
+				if mergedName != r.Name {
+					log.Warn("Repository ", r.Name, " (", r.URL, ") renamed to ", mergedName, ".")
+				}
+				mergedRepositories = append(mergedRepositories, RenvLockRepository{mergedName, r.URL})
+			}
+			names[r.Name] = mergedName
+		}
+		repositoryNames = append(repositoryNames, names)
+	}
+	return mergedRepositories, repositoryNames
+}
+
+// GetHighestVersion returns the highest of the versions (e.g. of R) found in the lockfiles fileNames,
+// and a description of the conflict if the lockfiles contain different versions.
+func GetHighestVersion(name string, versions []string, fileNames []string) (string, string) {
+	var highestVersion string
+	var distinctVersions, versionDescriptions []string
+	for i, v := range versions {
+		if v == "" {
+			continue
+		}
+		if !stringInSlice(v, distinctVersions) {
+			distinctVersions = append(distinctVersions, v)
+		}
+		versionDescriptions = append(versionDescriptions, v+" ("+fileNames[i]+")")
+		if highestVersion == "" || CheckIfVersionSufficient(v, ">", highestVersion) {
+			highestVersion = v
+		}
+	}
+	if len(distinctVersions) > 1 {
+		return highestVersion, name + " versions differ: " + strings.Join(versionDescriptions, ", ") +
+			"; using " + highestVersion
+	}
+	return highestVersion, ""
+}
+
+// GetMergedVersionConstraints returns, for each package, the version constraints of the package records
+// selected from renvLocks. selected maps the package names to the indices of the lockfiles from which
+// the packages are taken. If selected is nil, the constraints of all package records are returned.
+func GetMergedVersionConstraints(renvLocks []RenvLock, records []map[string]LockfileRecord,
+	fileNames []string, selected map[string]int) map[string][]RequiredVersion {
+	constraints := make(map[string][]RequiredVersion)
+	for i, renvLock := range renvLocks {
+		for k, p := range renvLock.Packages {
+			if j, ok := selected[k]; selected != nil && (!ok || j != i) {
+				continue
+			}
+			for _, d := range GetRecordDependencies(records[i][k], p) {
+				if d.VersionOperator == "" || d.DependencyType == suggests {
+					continue
+				}
+				constraints[d.DependencyName] = append(constraints[d.DependencyName], RequiredVersion{
+					DependencyVersion{d.VersionOperator, d.VersionValue}, k + " (" + fileNames[i] + ")",
+				})
+			}
+		}
+	}
+	return constraints
+}
+
+// SelectPackageVersion returns the index of the lockfile (one of candidates, sorted by the package version
+// from the highest) with the highest version of packageName satisfying all constraints. If no version
+// satisfies all constraints, the highest version is returned together with the description of the conflict.
+func SelectPackageVersion(packageName string, candidates []int, renvLocks []RenvLock,
+	constraints []RequiredVersion) (int, string) {
+	for _, i := range candidates {
+		satisfied := true
+		for _, c := range constraints {
+			if !CheckIfVersionSufficient(renvLocks[i].Packages[packageName].Version, c.VersionOperator, c.VersionValue) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return i, ""
+		}
+	}
+	var constraintDescriptions []string
+	for _, c := range constraints {
+		constraintDescriptions = append(constraintDescriptions,
+			c.RequiredBy+" requires "+c.VersionOperator+" "+c.VersionValue)
+	}
+	return candidates[0], packageName + ": no locked version satisfies all constraints (" +
+		strings.Join(constraintDescriptions, ", ") + "); using " + renvLocks[candidates[0]].Packages[packageName].Version
+}
+
+// GetPackageSource returns the description of the package source, e.g. 'Repository' or
+// 'GitHub (org1/package1@aaa111)'.
+func GetPackageSource(p PackageDescription) string {
+	if p.RemoteSha == "" {
+		return p.Source
+	}
+	return p.Source + " (" + p.RemoteUsername + "/" + p.RemoteRepo + "@" + p.RemoteSha + ")"
+}
+
+// GetPackageSourceConflict returns the description of the conflict if packageName is locked in the same
+// version as in the selected lockfile, but from a different source (e.g. from GitHub and from CRAN),
+// in any of the candidate lockfiles.
+func GetPackageSourceConflict(packageName string, candidates []int, selected int, renvLocks []RenvLock,
+	fileNames []string) string {
+	selectedPackage := renvLocks[selected].Packages[packageName]
+	var sourceDescriptions []string
+	var sourceDiffers bool
+	for _, i := range candidates {
+		p := renvLocks[i].Packages[packageName]
+		if p.Version != selectedPackage.Version {
+			continue
+		}
+		if GetPackageSource(p) != GetPackageSource(selectedPackage) {
+			sourceDiffers = true
+		}
+		sourceDescriptions = append(sourceDescriptions, GetPackageSource(p)+" ("+fileNames[i]+")")
+	}
+	if !sourceDiffers {
+		return ""
+	}
+	return packageName + " " + selectedPackage.Version + " sources differ: " + strings.Join(sourceDescriptions, ", ") +
+		"; using " + GetPackageSource(selectedPackage)
+}
+
+// SelectPackageVersions returns, for each package, the index of the lockfile from which the package is taken,
+// and the list of conflicts. packageLockfiles maps the package names to the indices of the lockfiles containing
+// the package, sorted by the package version, from the highest. The versions are first selected according to
+// the constraints of all package records, and then reselected according to the constraints of the selected
+// records only, until the selection doesn't change.
+func SelectPackageVersions(renvLocks []RenvLock, records []map[string]LockfileRecord, fileNames []string,
+	packageLockfiles map[string][]int) (map[string]int, []string) {
+	var selected map[string]int
+	var conflicts []string
+	for iteration := 0; iteration <= len(renvLocks); iteration++ {
+		constraints := GetMergedVersionConstraints(renvLocks, records, fileNames, selected)
+		newSelected := make(map[string]int)
+		conflicts = []string{}
+		for k, candidates := range packageLockfiles {
+			i, conflict := SelectPackageVersion(k, candidates, renvLocks, constraints[k])
+			newSelected[k] = i
+			if conflict != "" {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+		if reflect.DeepEqual(newSelected, selected) {
+			break
+		}
+		selected = newSelected
+	}
+	sort.Strings(conflicts)
+	return selected, conflicts
+}
+
+// GetMergedRecord returns the package record with the repository renamed according to repositoryNames.
+func GetMergedRecord(record LockfileRecord, repositoryNames map[string]string) LockfileRecord {
+	if repository, ok := record.Get("Repository"); ok {
+		var repositoryName string
+		if err := json.Unmarshal(repository.(json.RawMessage), &repositoryName); err == nil {
+			if mergedName, ok := repositoryNames[repositoryName]; ok && mergedName != repositoryName {
+				record.Set("Repository", mergedName)
+			}
+		}
+	}
+	return record
+}
+
+// MergeRenvLocks merges renvLocks (read from fileNames) together with their package records.
+// For each package, the highest version found in any of the lockfiles which satisfies the version
+// constraints of the packages selected from all lockfiles is used. It returns the merged lockfile and the list
+// of conflicts which couldn't be reconciled. Differences in the R and Bioconductor versions are only logged.
+func MergeRenvLocks(renvLocks []RenvLock, records []map[string]LockfileRecord,
+	fileNames []string) (FullRenvLock, []string) {
+	mergedRepositories, repositoryNames := MergeRepositories(renvLocks)
+	var rVersions, bioconductorVersions []string
+	for _, renvLock := range renvLocks {
+		rVersions = append(rVersions, renvLock.R.Version)
+		var bioconductorVersion string
+		if renvLock.Bioconductor != nil {
+			bioconductorVersion = renvLock.Bioconductor.Version
+		}
+		bioconductorVersions = append(bioconductorVersions, bioconductorVersion)
+	}
+	rVersion, warning := GetHighestVersion("R", rVersions, fileNames)
+	if warning != "" {
+		log.Warn(warning, ".")
+	}
+	mergedRenvLock := FullRenvLock{
+		RenvLockContents{rVersion, mergedRepositories}, nil, make(map[string]LockfileRecord),
+	}
+	bioconductorVersion, warning := GetHighestVersion("Bioconductor", bioconductorVersions, fileNames)
+	if warning != "" {
+		log.Warn(warning, ".")
+	}
+	if bioconductorVersion != "" {
+		mergedRenvLock.Bioconductor = &RenvLockBioconductor{bioconductorVersion}
+	}
+
+	// For each package, the indices of the lockfiles containing the package.
+	packageLockfiles := make(map[string][]int)
+	for i, renvLock := range renvLocks {
+		for k := range renvLock.Packages {
+			packageLockfiles[k] = append(packageLockfiles[k], i)
+		}
+	}
+	for k, candidates := range packageLockfiles {
+		// Sort the lockfiles by the package version, from the highest.
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return CheckIfVersionSufficient(
+				renvLocks[candidates[a]].Packages[k].Version, ">", renvLocks[candidates[b]].Packages[k].Version,
+			)
+		})
+	}
+	selected, conflicts := SelectPackageVersions(renvLocks, records, fileNames, packageLockfiles)
+	var packageNames []string
+	for k := range packageLockfiles {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	for _, k := range packageNames {
+		i := selected[k]
+		if conflict := GetPackageSourceConflict(k, packageLockfiles[k], i, renvLocks, fileNames); conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+		if len(packageLockfiles[k]) > 1 {
+			log.Debug("Using ", k, " version ", renvLocks[i].Packages[k].Version, " from ", fileNames[i], ".")
+		}
+		mergedRenvLock.Packages[k] = GetMergedRecord(records[i][k], repositoryNames[i])
+	}
+	return mergedRenvLock, conflicts
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getMergeTestLockfiles returns the lockfiles and their package records parsed from contents.
+func getMergeTestLockfiles(contents []string) ([]RenvLock, []map[string]LockfileRecord) {
+	var renvLocks []RenvLock
+	var records []map[string]LockfileRecord
+	for _, content := range contents {
+		var renvLock RenvLock
+		err := json.Unmarshal([]byte(content), &renvLock)
+		checkError(err)
+		renvLocks = append(renvLocks, renvLock)
+		records = append(records, GetLockfileRecords([]byte(content)))
+	}
+	return renvLocks, records
+}
+
+func Test_MergeRepositories(t *testing.T) {
+	renvLocks := []RenvLock{
+		{RenvLockContents{"", []RenvLockRepository{{"CRAN", "https://cran1.example.com"}}}, nil, nil},
+		{RenvLockContents{"", []RenvLockRepository{
+			{"CRAN", "https://cran2.example.com"}, {"PPM", "https://cran1.example.com"},
+		}}, nil, nil},
+		{RenvLockContents{"", []RenvLockRepository{{"CRAN", "https://cran3.example.com"}}}, nil, nil},
+	}
+	mergedRepositories, repositoryNames := MergeRepositories(renvLocks)
+	assert.Equal(t, mergedRepositories, []RenvLockRepository{
+		{"CRAN", "https://cran1.example.com"},
+		{"CRAN_2", "https://cran2.example.com"},
+		{"CRAN_3", "https://cran3.example.com"},
+	})
+	assert.Equal(t, repositoryNames, []map[string]string{
+		{"CRAN": "CRAN"}, {"CRAN": "CRAN_2", "PPM": "CRAN"}, {"CRAN": "CRAN_3"},
+	})
+}
+
+func Test_MergeRenvLocks(t *testing.T) {
+	renvLocks, records := getMergeTestLockfiles([]string{`{
+  "R": {"Version": "4.3.2", "Repositories": [{"Name": "CRAN", "URL": "https://cran1.example.com"}]},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN",
+      "Imports": ["package2 (< 3.0.0)"]},
+    "package2": {"Package": "package2", "Version": "2.0.0", "Source": "Repository", "Repository": "CRAN"},
+    "package4": {"Package": "package4", "Version": "4.0.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`, `{
+  "R": {"Version": "4.4.0", "Repositories": [{"Name": "CRAN", "URL": "https://cran2.example.com"}]},
+  "Packages": {
+    "package2": {"Package": "package2", "Version": "3.0.0", "Source": "Repository", "Repository": "CRAN"},
+    "package3": {"Package": "package3", "Version": "3.0.0", "Source": "Repository", "Repository": "CRAN",
+      "Imports": ["package4 (>= 4.1.0)"], "Title": "Package 3"},
+    "package4": {"Package": "package4", "Version": "4.0.1", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`})
+	mergedRenvLock, conflicts := MergeRenvLocks(renvLocks, records, []string{"a.lock", "b.lock"})
+	assert.Equal(t, mergedRenvLock.R, RenvLockContents{"4.4.0", []RenvLockRepository{
+		{"CRAN", "https://cran1.example.com"}, {"CRAN_2", "https://cran2.example.com"},
+	}})
+	assert.Nil(t, mergedRenvLock.Bioconductor)
+	packagesJSON, err := marshalWithoutEscaping(mergedRenvLock.Packages)
+	assert.Nil(t, err)
+	assert.Equal(t, string(packagesJSON), `{`+
+		`"package1":{"Package":"package1","Version":"1.0.0","Source":"Repository","Repository":"CRAN",`+
+		`"Imports":["package2 (< 3.0.0)"]},`+
+		`"package2":{"Package":"package2","Version":"2.0.0","Source":"Repository","Repository":"CRAN"},`+
+		`"package3":{"Package":"package3","Version":"3.0.0","Source":"Repository","Repository":"CRAN_2",`+
+		`"Imports":["package4 (>= 4.1.0)"],"Title":"Package 3"},`+
+		`"package4":{"Package":"package4","Version":"4.0.1","Source":"Repository","Repository":"CRAN_2"}}`)
+	// Different R versions are not a conflict.
+	assert.Equal(t, conflicts, []string{
+		"package4: no locked version satisfies all constraints (package3 (b.lock) requires >= 4.1.0); using 4.0.1",
+	})
+}
+
+func Test_MergeRenvLocksSelectedConstraints(t *testing.T) {
+	renvLocks, records := getMergeTestLockfiles([]string{`{
+  "R": {"Version": "4.3.2", "Repositories": []},
+  "Bioconductor": {"Version": "3.18"},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN",
+      "Imports": ["package2 (< 2.0.0)"]},
+    "package2": {"Package": "package2", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`, `{
+  "R": {"Version": "4.3.2", "Repositories": []},
+  "Bioconductor": {"Version": "3.19"},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "2.0.0", "Source": "Repository", "Repository": "CRAN"},
+    "package2": {"Package": "package2", "Version": "2.0.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`})
+	mergedRenvLock, conflicts := MergeRenvLocks(renvLocks, records, []string{"a.lock", "b.lock"})
+	assert.Equal(t, *mergedRenvLock.Bioconductor, RenvLockBioconductor{"3.19"})
+	packagesJSON, err := marshalWithoutEscaping(mergedRenvLock.Packages)
+	assert.Nil(t, err)
+	// The constraint of package1 1.0.0 doesn't apply, because package1 2.0.0 is selected.
+	assert.Equal(t, string(packagesJSON), `{`+
+		`"package1":{"Package":"package1","Version":"2.0.0","Source":"Repository","Repository":"CRAN"},`+
+		`"package2":{"Package":"package2","Version":"2.0.0","Source":"Repository","Repository":"CRAN"}}`)
+	assert.Equal(t, conflicts, []string{})
+}
+
+func Test_MergeRenvLocksSourceConflict(t *testing.T) {
+	renvLocks, records := getMergeTestLockfiles([]string{`{
+  "R": {"Version": "4.3.2", "Repositories": []},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN"},
+    "package2": {"Package": "package2", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`, `{
+  "R": {"Version": "4.3.2", "Repositories": []},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "GitHub", "RemoteType": "github",
+      "RemoteHost": "api.github.com", "RemoteUsername": "org1", "RemoteRepo": "package1", "RemoteSha": "aaa111"},
+    "package2": {"Package": "package2", "Version": "1.0.0", "Source": "Repository", "Repository": "CRAN"}
+  }
+}`})
+	_, conflicts := MergeRenvLocks(renvLocks, records, []string{"a.lock", "b.lock"})
+	assert.Equal(t, conflicts, []string{
+		"package1 1.0.0 sources differ: Repository (a.lock), GitHub (org1/package1@aaa111) (b.lock); using Repository",
+	})
+}
+
+func Test_GetPackageSource(t *testing.T) {
+	assert.Equal(t, GetPackageSource(PackageDescription{
+		"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "", nil,
+	}), "Repository")
+	assert.Equal(t, GetPackageSource(PackageDescription{
+		"package1", "1.0.0", "GitLab", "", []Dependency{},
+		"gitlab", "https://gitlab.example.com", "group1/group2", "package1", "", "main", "bbb222", []string{}, "", nil,
+	}), "GitLab (group1/group2/package1@bbb222)")
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+const exactVersionOperator = "=="
+
+// ParseVersionConstraints processes comma-separated version constraints such as '>= 1.2.0, < 2.0.0'
+// and returns a list of DependencyVersion structures. A version without an operator is treated
+// as an exact version requirement.
+func ParseVersionConstraints(version string) []DependencyVersion {
+	var constraints []DependencyVersion
+	re := regexp.MustCompile(`^(==|>=|<=|>|<)?\s*([0-9][0-9.\-]*)$`)
+	for _, constraint := range strings.Split(version, ",") {
+		constraint = strings.TrimSpace(constraint)
+		if constraint == "" {
+			continue
+		}
+		match := re.FindStringSubmatch(constraint)
+		if match == nil {
+			log.Fatal("Incorrect format of version constraint '", constraint, "'. Please try e.g. '== 1.2.3' or '>= 1.2'.")
+		}
+		operator := match[1]
+		if operator == "" {
+			operator = exactVersionOperator
+		}
+		constraints = append(constraints, DependencyVersion{operator, match[2]})
+	}
+	return constraints
+}
+
+// CheckIfVersionConstraintsSatisfied checks if version fulfills all of the constraints.
+func CheckIfVersionConstraintsSatisfied(version string, constraints []DependencyVersion) bool {
+	for _, c := range constraints {
+		if !CheckIfVersionSufficient(version, c.VersionOperator, c.VersionValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetExactVersion returns the version required by an exact ('==') constraint,
+// or an empty string if there's no such constraint.
+func GetExactVersion(constraints []DependencyVersion) string {
+	for _, c := range constraints {
+		if c.VersionOperator == exactVersionOperator {
+			return c.VersionValue
+		}
+	}
+	return ""
+}
+
+// GetFailedVersionConstraint returns the constraint which version doesn't fulfill.
+// If version is empty (the package hasn't been found), the exact ('==') constraint
+// is returned if present, and otherwise the first of the constraints.
+func GetFailedVersionConstraint(version string, constraints []DependencyVersion) DependencyVersion {
+	if version != "" {
+		for _, c := range constraints {
+			if !CheckIfVersionSufficient(version, c.VersionOperator, c.VersionValue) {
+				return c
+			}
+		}
+	}
+	for _, c := range constraints {
+		if c.VersionOperator == exactVersionOperator {
+			return c
+		}
+	}
+	return constraints[0]
+}
+
+// GetMissingPackageConstraint returns the version constraint which should be reported for a package
+// which couldn't be found in searchedRepositories. If the package has been overridden with version constraints,
+// the override constraint which couldn't be satisfied is returned instead of the requirement
+// versionOperator versionValue.
+func GetMissingPackageConstraint(packageName string, versionOperator string, versionValue string,
+	override PackageOverride, searchedRepositories []string,
+	packagesFiles map[string]PackagesFile) (string, string) {
+	if len(override.VersionConstraints) == 0 {
+		return versionOperator, versionValue
+	}
+	p, _ := FindPackageInRepositories(packageName, "", "", searchedRepositories, packagesFiles)
+	failedConstraint := GetFailedVersionConstraint(p.Version, override.VersionConstraints)
+	return failedConstraint.VersionOperator, failedConstraint.VersionValue
+}
+
+// GetArchivedOverridePackage returns the package pinned by the override to an exact version available only
+// in the archive of a repository, together with the URL of that repository, or an empty URL if there's no such
+// repository. The returned package is the one listed in the PACKAGES file of the repository, so the dependencies
+// of the listed version are used for the archived version.
+func GetArchivedOverridePackage(override PackageOverride,
+	packagesFiles map[string]PackagesFile) (PackageDescription, string) {
+	if GetExactVersion(override.VersionConstraints) == "" || len(override.ArchivedRepositoryURLs) == 0 {
+		return PackageDescription{}, ""
+	}
+	repositoryURL := override.ArchivedRepositoryURLs[0]
+	for _, p := range packagesFiles[repositoryURL].Packages {
+		if p.Package == override.Package {
+			return p, repositoryURL
+		}
+	}
+	return PackageDescription{}, ""
+}
+
+// ParsePinList processes the comma-separated list of pins provided via --pin flag.
+// Each pin follows the pattern: '<package><operator><version>@<repository>', where both the
+// version constraint and the repository are optional. Instead of the repository name,
+// a URL to the raw DESCRIPTION file in a git repository can be provided.
+// Multiple pins for the same package are combined, e.g. 'dplyr>=1.1.0,dplyr<2.0.0'.
+func ParsePinList(pinList string) []PackageOverride {
+	var pins []PackageOverride
+	pinIndices := make(map[string]int)
+	re := regexp.MustCompile(`^([A-Za-z0-9.]+)\s*((?:==|>=|<=|>|<)\s*[0-9][0-9.\-]*)?\s*(?:@(.+))?$`)
+	for _, pin := range strings.Split(pinList, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		match := re.FindStringSubmatch(pin)
+		if match == nil {
+			log.Fatal(
+				"Incorrect format of pin '", pin, "'. Please try: ",
+				"'package1==1.2.3,package2@Repo1,package3>=1.0@Repo2,package4@<DESCRIPTION URL>'",
+			)
+		}
+		override := PackageOverride{Package: match[1], Version: match[2]}
+		if strings.HasPrefix(match[3], https) {
+			override.Remote = match[3]
+		} else {
+			override.Repository = match[3]
+		}
+		i, ok := pinIndices[override.Package]
+		if !ok {
+			pinIndices[override.Package] = len(pins)
+			pins = append(pins, override)
+			continue
+		}
+		// Combine the pin with the previous pin for the same package.
+		if override.Version != "" {
+			if pins[i].Version != "" {
+				pins[i].Version += ", "
+			}
+			pins[i].Version += override.Version
+		}
+		if override.Repository != "" {
+			pins[i].Repository = override.Repository
+		}
+		if override.Remote != "" {
+			pins[i].Remote = override.Remote
+		}
+	}
+	return pins
+}
+
+// ParsePackageOverrides combines the overrides defined in the YAML configuration with the
+// pins provided via --pin flag, which take precedence over the configuration file.
+// It returns a map from package name to the processed override.
+func ParsePackageOverrides(overrides []PackageOverride, pinList string,
+	repositoryMap map[string]string) map[string]PackageOverride {
+	outputOverrides := make(map[string]PackageOverride)
+	for _, o := range append(overrides, ParsePinList(pinList)...) {
+		if o.Package == "" {
+			log.Fatal("Package name missing in packageOverrides entry.")
+		}
+		o.VersionConstraints = ParseVersionConstraints(o.Version)
+		if o.Repository != "" {
+			repositoryURL, ok := repositoryMap[o.Repository]
+			if !ok {
+				log.Fatal(
+					"Repository ", o.Repository, " referenced by the override for package ", o.Package,
+					" has not been defined in the input repositories.",
+				)
+			}
+			o.RepositoryURL = repositoryURL
+		}
+		if o.Remote != "" && (o.Repository != "" || o.Version != "") {
+			log.Warn(
+				"Package ", o.Package, " will be downloaded from ", o.Remote,
+				" so the repository and the version constraints set by the override will be ignored.",
+			)
+		}
+		outputOverrides[o.Package] = o
+	}
+	for packageName, o := range outputOverrides {
+		log.Debug("Override for package ", packageName, ": version = '", o.Version,
+			"', repository = '", o.Repository, "', remote = '", o.Remote, "'")
+	}
+	return outputOverrides
+}
+
+// DownloadPackageOverrideRemotes downloads and parses the DESCRIPTION files of overrides
+// which replace a package with a version from a git repository.
+func DownloadPackageOverrideRemotes(packageOverrides map[string]PackageOverride,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) {
+	for packageName, o := range packageOverrides {
+		if o.Remote == "" {
+			continue
+		}
+		gitPackages := ParseDescriptionFileList(DownloadDescriptionFiles([]string{o.Remote}, downloadFileFunction))
+		if len(gitPackages) != 1 || gitPackages[0].Package != packageName {
+			log.Fatal("Could not retrieve package ", packageName, " from ", o.Remote)
+		}
+		o.GitPackage = gitPackages[0]
+		packageOverrides[packageName] = o
+	}
+}
+
+// FindArchivedPackageOverrides checks, for each override pinning an exact package version,
+// in which of the searched repositories that version is only available in the archive.
+// This is the case when the repository lists the package in a higher version in its PACKAGES file,
+// and the pinned version appears in the archive index of the package.
+func FindArchivedPackageOverrides(packageOverrides map[string]PackageOverride,
+	packagesFiles map[string]PackagesFile, repositoryList []string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) {
+	for packageName, o := range packageOverrides {
+		exactVersion := GetExactVersion(o.VersionConstraints)
+		if exactVersion == "" || o.GitPackage.Package != "" {
+			continue
+		}
+		searchedRepositories := repositoryList
+		if o.RepositoryURL != "" {
+			searchedRepositories = []string{o.RepositoryURL}
+		}
+		o.ArchivedRepositoryURLs = []string{}
+		for _, r := range searchedRepositories {
+			for _, p := range packagesFiles[r].Packages {
+				if p.Package != packageName {
+					continue
+				}
+				if CheckIfVersionSufficient(p.Version, ">", exactVersion) &&
+					stringInSlice(exactVersion, GetArchivedPackageVersions(r, packageName, downloadFileFunction)) {
+					o.ArchivedRepositoryURLs = append(o.ArchivedRepositoryURLs, r)
+				}
+				break
+			}
+		}
+		packageOverrides[packageName] = o
+	}
+}
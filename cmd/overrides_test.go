@@ -0,0 +1,152 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseVersionConstraints(t *testing.T) {
+	assert.Equal(t, ParseVersionConstraints("3.4.4"), []DependencyVersion{{"==", "3.4.4"}})
+	assert.Equal(t, ParseVersionConstraints(">= 1.2.0, < 2.0.0"),
+		[]DependencyVersion{{">=", "1.2.0"}, {"<", "2.0.0"}})
+	assert.Empty(t, ParseVersionConstraints(""))
+}
+
+func Test_CheckIfVersionConstraintsSatisfied(t *testing.T) {
+	constraints := []DependencyVersion{{">=", "1.2.0"}, {"<", "2.0.0"}}
+	assert.True(t, CheckIfVersionConstraintsSatisfied("1.2.0", constraints))
+	assert.True(t, CheckIfVersionConstraintsSatisfied("1.9.9", constraints))
+	assert.False(t, CheckIfVersionConstraintsSatisfied("2.0.0", constraints))
+	assert.False(t, CheckIfVersionConstraintsSatisfied("1.1", constraints))
+	assert.True(t, CheckIfVersionConstraintsSatisfied("1.1", []DependencyVersion{}))
+}
+
+func Test_ParsePinList(t *testing.T) {
+	pins := ParsePinList(
+		"ggplot2==3.4.4,Matrix@CRAN,dplyr>=1.1.0,dplyr<2.0.0@Repo1," +
+			"package1@https://raw.githubusercontent.com/org1/package1/main/DESCRIPTION",
+	)
+	assert.Equal(t, pins, []PackageOverride{
+		{Package: "ggplot2", Version: "==3.4.4"},
+		{Package: "Matrix", Repository: "CRAN"},
+		{Package: "dplyr", Version: ">=1.1.0, <2.0.0", Repository: "Repo1"},
+		{Package: "package1", Remote: "https://raw.githubusercontent.com/org1/package1/main/DESCRIPTION"},
+	})
+}
+
+func Test_ParsePackageOverrides(t *testing.T) {
+	packageOverrides := ParsePackageOverrides(
+		[]PackageOverride{
+			{Package: "ggplot2", Version: "3.4.4", Repository: "Repo1"},
+			{Package: "Matrix", Repository: "Repo2"},
+		},
+		"ggplot2>=3.5",
+		map[string]string{
+			"Repo1": "https://repo1.example.com/repo1",
+			"Repo2": "https://repo2.example.com/repo2",
+		},
+	)
+	assert.Equal(t, packageOverrides, map[string]PackageOverride{
+		"ggplot2": {
+			Package: "ggplot2", Version: ">=3.5",
+			VersionConstraints: []DependencyVersion{{">=", "3.5"}},
+		},
+		"Matrix": {
+			Package: "Matrix", Repository: "Repo2",
+			RepositoryURL: "https://repo2.example.com/repo2",
+		},
+	})
+}
+
+func Test_GetFailedVersionConstraint(t *testing.T) {
+	constraints := []DependencyVersion{{">=", "1.2.0"}, {"<", "2.0.0"}}
+	assert.Equal(t, GetFailedVersionConstraint("2.1.0", constraints), DependencyVersion{"<", "2.0.0"})
+	assert.Equal(t, GetFailedVersionConstraint("1.1.0", constraints), DependencyVersion{">=", "1.2.0"})
+	assert.Equal(t, GetFailedVersionConstraint("", constraints), DependencyVersion{">=", "1.2.0"})
+	assert.Equal(t,
+		GetFailedVersionConstraint("", []DependencyVersion{{">=", "1.0"}, {"==", "1.5"}}),
+		DependencyVersion{"==", "1.5"},
+	)
+}
+
+func Test_FindArchivedPackageOverrides(t *testing.T) {
+	packagesFiles := map[string]PackagesFile{
+		"https://repo1.example.com": {
+			[]PackageDescription{
+				{
+					"dplyr", "1.1.4", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	repositoryList := []string{"https://repo1.example.com"}
+	for _, tc := range []struct {
+		version  string
+		expected []string
+	}{
+		// Lower version present in the archive.
+		{"1.0.10", []string{"https://repo1.example.com"}},
+		// Version higher than the one available in the repository.
+		{"1.2.0", []string{}},
+		// Lower version absent from the archive.
+		{"1.0.5", []string{}},
+	} {
+		packageOverrides := map[string]PackageOverride{
+			"dplyr": {
+				Package: "dplyr", Version: tc.version,
+				VersionConstraints: []DependencyVersion{{"==", tc.version}},
+			},
+		}
+		FindArchivedPackageOverrides(packageOverrides, packagesFiles, repositoryList, mockedDownloadArchiveFile)
+		assert.Equal(t, packageOverrides["dplyr"].ArchivedRepositoryURLs, tc.expected)
+	}
+}
+
+func Test_GetMissingPackageConstraint(t *testing.T) {
+	packagesFiles := map[string]PackagesFile{
+		"https://repo1.example.com": {
+			[]PackageDescription{
+				{
+					"dplyr", "1.1.4", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	repositoryList := []string{"https://repo1.example.com"}
+	versionOperator, versionValue := GetMissingPackageConstraint(
+		"dplyr", ">=", "1.0.0", PackageOverride{}, repositoryList, packagesFiles,
+	)
+	assert.Equal(t, versionOperator, ">=")
+	assert.Equal(t, versionValue, "1.0.0")
+	versionOperator, versionValue = GetMissingPackageConstraint(
+		"dplyr", ">=", "1.0.0",
+		PackageOverride{
+			Package: "dplyr", Version: ">= 1.0.0, < 1.1.0",
+			VersionConstraints: []DependencyVersion{{">=", "1.0.0"}, {"<", "1.1.0"}},
+		},
+		repositoryList, packagesFiles,
+	)
+	assert.Equal(t, versionOperator, "<")
+	assert.Equal(t, versionValue, "1.1.0")
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const pakLockfileVersion = 1
+const pakPlatformSource = "source"
+
+// GetPakPlatform returns the pak platform name of binary packages for the target operating system
+// and CPU architecture, e.g. 'x86_64-w64-mingw32'.
+func GetPakPlatform(targetOS string, targetArch string) string {
+	switch targetOS {
+	case "windows":
+		return "x86_64-w64-mingw32"
+	case "macos":
+		if targetArch == "x86_64" {
+			return "x86_64-apple-darwin17.0"
+		}
+		return "aarch64-apple-darwin20"
+	}
+	return pakPlatformSource
+}
+
+// GetPakOS returns the R platform string of the target operating system and CPU architecture, as saved
+// by pak in the lockfile header, e.g. 'x86_64-pc-linux-gnu'.
+func GetPakOS(targetOS string, targetArch string) string {
+	switch targetOS {
+	case "":
+		return ""
+	case "linux":
+		if targetArch == "" {
+			targetArch = "x86_64"
+		}
+		return targetArch + "-pc-linux-gnu"
+	}
+	return GetPakPlatform(targetOS, targetArch)
+}
+
+// GetPakRef returns the pak package reference for the package p, pinned to the locked commit
+// in case of packages from git repositories.
+func GetPakRef(p PackageDescription) string {
+	repositoryPath := p.RemoteUsername + "/" + p.RemoteRepo
+	if p.RemoteSubdir != "" {
+		repositoryPath += "/" + p.RemoteSubdir
+	}
+	switch p.Source {
+	case GitHub:
+		return repositoryPath + "@" + p.RemoteSha
+	case GitLab:
+		return "gitlab::" + repositoryPath + "@" + p.RemoteSha
+	}
+	return p.Package
+}
+
+// GetPakLockPackage converts the package p from the renv.lock into a pak pkg.lock package.
+// repositoryURL is the URL of the repository from which the package is downloaded (if any),
+// and binaryPlatform is the pak platform of binary packages from that repository.
+func GetPakLockPackage(p PackageDescription, repositoryURL string, direct bool, binaryPlatform string) PakLockPackage {
+	pakPackage := PakLockPackage{
+		GetPakRef(p), p.Package, p.Version, "standard", direct, false, p.Requirements, false,
+		p.Fields["NeedsCompilation"] == "yes", map[string]string{}, []string{}, "", pakPlatformSource, "*",
+		direct, p.Fields["License"], []string{depends, imports, linkingTo}, []string{}, "", "",
+	}
+	if pakPackage.Dependencies == nil {
+		pakPackage.Dependencies = []string{}
+	}
+	pakPackage.Metadata["RemotePkgRef"] = pakPackage.Ref
+	switch p.Source {
+	case GitHub, GitLab:
+		pakPackage.Type = strings.ToLower(p.Source)
+		for _, f := range []PakMetadataField{
+			{"RemoteType", p.RemoteType}, {"RemoteHost", p.RemoteHost}, {"RemoteUsername", p.RemoteUsername},
+			{"RemoteRepo", p.RemoteRepo}, {"RemoteSubdir", p.RemoteSubdir}, {"RemoteRef", p.RemoteRef},
+			{"RemoteSha", p.RemoteSha},
+		} {
+			if f.Value != "" {
+				pakPackage.Metadata[f.Name] = f.Value
+			}
+		}
+		if p.Source == GitHub {
+			pakPackage.Sources = []string{
+				GetGitHubAPIURL(p.RemoteHost) + "/repos/" + p.RemoteUsername + "/" + p.RemoteRepo +
+					"/tarball/" + p.RemoteSha,
+			}
+		} else {
+			remoteHost := strings.TrimSuffix(GetGitRepositoryURL(p), "/"+p.RemoteUsername+"/"+p.RemoteRepo)
+			pakPackage.Sources = []string{
+				remoteHost + "/api/v4/projects/" + url.PathEscape(p.RemoteUsername+"/"+p.RemoteRepo) +
+					"/repository/archive.tar.gz?sha=" + p.RemoteSha,
+			}
+		}
+		pakPackage.Target = "src/contrib/" + p.Package + "_" + p.Version + "_" + p.RemoteSha + ".tar.gz"
+	default:
+		pakPackage.Metadata["RemoteType"] = "standard"
+		pakPackage.Metadata["RemoteRef"] = p.Package
+		pakPackage.Metadata["RemoteRepos"] = repositoryURL
+		pakPackage.Metadata["RemoteSha"] = p.Version
+		pakPackage.RepoType = "cran"
+		if strings.Contains(repositoryURL, "bioconductor") {
+			pakPackage.RepoType = "bioc"
+		}
+		downloadURL := GetPackageDownloadURL(repositoryURL, p.Package, p.Version, "")
+		if strings.Contains(repositoryURL, "/bin/") {
+			pakPackage.Binary = true
+			pakPackage.Platform = binaryPlatform
+			pakPackage.RVersion = strings.Split(p.Fields["Built"], ";")[0]
+			pakPackage.RVersion = strings.TrimSpace(strings.TrimPrefix(pakPackage.RVersion, "R "))
+			pakPackage.Sources = []string{downloadURL}
+			pakPackage.Target = "bin/" + strings.SplitN(downloadURL, "/bin/", 2)[1]
+		} else {
+			pakPackage.Sources = []string{
+				downloadURL,
+				repositoryURL + "/src/contrib/Archive/" + p.Package + "/" + p.Package + "_" + p.Version + ".tar.gz",
+			}
+			pakPackage.Target = "src/contrib/" + p.Package + "_" + p.Version + ".tar.gz"
+		}
+		pakPackage.Metadata["RemotePkgPlatform"] = pakPackage.Platform
+	}
+	return pakPackage
+}
+
+// GeneratePakLock converts renvLock into pak's pkg.lock structure. The inputPackages are marked
+// as direct dependencies, and the targetPlatform and rVersion are saved in the lockfile header.
+// The packages are sorted by name.
+func GeneratePakLock(renvLock RenvLock, inputPackages []PackageDescription, targetPlatform string,
+	rVersion string) PakLock {
+	targetOS, targetArch := ParseTargetPlatform(targetPlatform)
+	binaryPlatform := GetPakPlatform(targetOS, targetArch)
+	pakLock := PakLock{
+		pakLockfileVersion, GetPakOS(targetOS, targetArch), rVersion, binaryPlatform, []PakLockPackage{},
+	}
+	repositoryURLs := make(map[string]string)
+	for _, r := range renvLock.R.Repositories {
+		repositoryURLs[r.Name] = r.URL
+	}
+	var directPackages, packageNames []string
+	for _, p := range inputPackages {
+		directPackages = append(directPackages, p.Package)
+	}
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	for _, k := range packageNames {
+		p := renvLock.Packages[k]
+		pakLock.Packages = append(pakLock.Packages, GetPakLockPackage(
+			p, repositoryURLs[p.Repository], stringInSlice(k, directPackages), binaryPlatform,
+		))
+	}
+	return pakLock
+}
+
+// GetPakLockfileName returns the name of pak's pkg.lock file for the target, based on the configured
+// pakLockfileName, e.g. 'pkg-linux.lock' for 'pkg.lock' and target named 'linux'.
+func GetPakLockfileName(pakLockfileName string, targetName string) string {
+	if targetName == "" {
+		return pakLockfileName
+	}
+	return strings.TrimSuffix(pakLockfileName, ".lock") + "-" + targetName + ".lock"
+}
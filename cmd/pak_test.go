@@ -0,0 +1,150 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetPakPlatform(t *testing.T) {
+	assert.Equal(t, GetPakPlatform("linux", "x86_64"), "source")
+	assert.Equal(t, GetPakPlatform("windows", "x86_64"), "x86_64-w64-mingw32")
+	assert.Equal(t, GetPakPlatform("macos", "aarch64"), "aarch64-apple-darwin20")
+	assert.Equal(t, GetPakPlatform("macos", "x86_64"), "x86_64-apple-darwin17.0")
+}
+
+func Test_GetPakLockfileName(t *testing.T) {
+	assert.Equal(t, GetPakLockfileName("pkg.lock", ""), "pkg.lock")
+	assert.Equal(t, GetPakLockfileName("pkg.lock", "windows"), "pkg-windows.lock")
+}
+
+func Test_GetPakOS(t *testing.T) {
+	assert.Equal(t, GetPakOS("linux", ""), "x86_64-pc-linux-gnu")
+	assert.Equal(t, GetPakOS("linux", "aarch64"), "aarch64-pc-linux-gnu")
+	assert.Equal(t, GetPakOS("windows", "x86_64"), "x86_64-w64-mingw32")
+	assert.Equal(t, GetPakOS("macos", "aarch64"), "aarch64-apple-darwin20")
+	assert.Equal(t, GetPakOS("", ""), "")
+}
+
+func Test_GetPakLockPackageGitHubEnterprise(t *testing.T) {
+	p := PackageDescription{
+		"package1", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "github.example.com/api/v3", "org1", "package1", "", "main", "aaa111", []string{}, "", nil,
+	}
+	pakPackage := GetPakLockPackage(p, "", true, pakPlatformSource)
+	assert.Equal(t, pakPackage.Sources, []string{
+		"https://github.example.com/api/v3/repos/org1/package1/tarball/aaa111",
+	})
+	assert.Equal(t, pakPackage.Metadata["RemoteHost"], "github.example.com/api/v3")
+}
+
+func Test_GeneratePakLock(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"4.3.2", []RenvLockRepository{
+			{"CRAN", "https://cran.example.com"},
+			{"BioC", "https://bioconductor.org/packages/3.18/bioc"},
+		}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package3"}, "",
+				map[string]string{"License": "MIT + file LICENSE", "NeedsCompilation": "yes"},
+			},
+			"package2": {
+				"package2", "2.0.0", "Repository", "BioC", []Dependency{},
+				"", "", "", "", "", "", "", nil, "", map[string]string{"License": "GPL-3"},
+			},
+			"package3": {
+				"package3", "3.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "repo3", "pkg", "main", "aaa111", []string{}, "", nil,
+			},
+			"package4": {
+				"package4", "4.0.0", "GitLab", "", []Dependency{},
+				"gitlab", "https://gitlab.example.com", "group1/subgroup1", "package4", "", "v4.0.0",
+				"bbb222", []string{}, "", nil,
+			},
+		},
+	}
+	inputPackages := []PackageDescription{{Package: "package1"}, {Package: "package4"}}
+	pakLock := GeneratePakLock(renvLock, inputPackages, "linux/x86_64", "4.3.2")
+	assert.Equal(t, pakLock.LockfileVersion, 1)
+	assert.Equal(t, pakLock.OS, "x86_64-pc-linux-gnu")
+	assert.Equal(t, pakLock.RVersion, "4.3.2")
+	assert.Equal(t, pakLock.Platform, "source")
+	assert.Equal(t, len(pakLock.Packages), 4)
+
+	package1 := pakLock.Packages[0]
+	assert.Equal(t, package1.Ref, "package1")
+	assert.Equal(t, package1.Type, "standard")
+	assert.True(t, package1.Direct)
+	assert.True(t, package1.NeedsCompilation)
+	assert.Equal(t, package1.License, "MIT + file LICENSE")
+	assert.Equal(t, package1.Dependencies, []string{"package3"})
+	assert.Equal(t, package1.RepoType, "cran")
+	assert.Equal(t, package1.Sources, []string{
+		"https://cran.example.com/src/contrib/package1_1.0.0.tar.gz",
+		"https://cran.example.com/src/contrib/Archive/package1/package1_1.0.0.tar.gz",
+	})
+	assert.Equal(t, package1.Target, "src/contrib/package1_1.0.0.tar.gz")
+	assert.Equal(t, package1.Metadata["RemoteRepos"], "https://cran.example.com")
+
+	package2 := pakLock.Packages[1]
+	assert.False(t, package2.Direct)
+	assert.Equal(t, package2.RepoType, "bioc")
+	assert.Equal(t, package2.Dependencies, []string{})
+
+	package3 := pakLock.Packages[2]
+	assert.Equal(t, package3.Ref, "org1/repo3/pkg@aaa111")
+	assert.Equal(t, package3.Type, "github")
+	assert.Equal(t, package3.Sources, []string{"https://api.github.com/repos/org1/repo3/tarball/aaa111"})
+	assert.Equal(t, package3.Target, "src/contrib/package3_3.0.0_aaa111.tar.gz")
+	assert.Equal(t, package3.Metadata["RemoteSubdir"], "pkg")
+
+	package4 := pakLock.Packages[3]
+	assert.Equal(t, package4.Ref, "gitlab::group1/subgroup1/package4@bbb222")
+	assert.Equal(t, package4.Type, "gitlab")
+	assert.True(t, package4.Direct)
+	assert.Equal(t, package4.Sources, []string{
+		"https://gitlab.example.com/api/v4/projects/group1%2Fsubgroup1%2Fpackage4/repository/archive.tar.gz?sha=bbb222",
+	})
+}
+
+func Test_GeneratePakLockBinary(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"4.3.2", []RenvLockRepository{
+			{"CRAN", "https://cran.example.com/bin/windows/contrib/4.3"},
+		}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "",
+				map[string]string{"Built": "R 4.3.2; x86_64-w64-mingw32; 2024-01-01 00:00:00 UTC; windows"},
+			},
+		},
+	}
+	pakLock := GeneratePakLock(renvLock, []PackageDescription{}, "windows/x86_64", "4.3.2")
+	assert.Equal(t, pakLock.OS, "x86_64-w64-mingw32")
+	assert.Equal(t, pakLock.Platform, "x86_64-w64-mingw32")
+	package1 := pakLock.Packages[0]
+	assert.True(t, package1.Binary)
+	assert.Equal(t, package1.Platform, "x86_64-w64-mingw32")
+	assert.Equal(t, package1.RVersion, "4.3.2")
+	assert.Equal(t, package1.Sources, []string{"https://cran.example.com/bin/windows/contrib/4.3/package1_1.0.0.zip"})
+	assert.Equal(t, package1.Target, "bin/windows/contrib/4.3/package1_1.0.0.zip")
+}
@@ -56,11 +56,6 @@ func ProcessPackagesFile(content string) PackagesFile {
 		firstLine := strings.Split(lineGroup, "\n")[0]
 		packageName := strings.ReplaceAll(firstLine, "Package: ", "")
 		cleaned := CleanDescriptionOrPackagesEntry(lineGroup, false)
-		if cleaned == "" {
-			// Package entry pointing to a "Path:" subdirectory encountered.
-			// Such package entries are skipped altogether.
-			continue
-		}
 		packageMap := make(map[string]string)
 		err := yaml.Unmarshal([]byte(cleaned), &packageMap)
 		if err != nil {
@@ -68,17 +63,59 @@ func ProcessPackagesFile(content string) PackagesFile {
 		}
 		var packageDependencies []Dependency
 		ProcessDependencyFields(packageMap, &packageDependencies)
-		allPackages.Packages = append(
-			allPackages.Packages,
-			PackageDescription{
-				packageName, packageMap["Version"], "", "", packageDependencies,
-				"", "", "", "", "", "", "", []string{}, "",
-			},
-		)
+		packageDescription := PackageDescription{
+			packageName, packageMap["Version"], "", "", packageDependencies,
+			"", "", "", "", "", "", "", []string{}, "", ParseDCF(lineGroup),
+		}
+		if path, ok := packageMap["Path"]; ok {
+			// This means that the package is located in a subdirectory mentioned in this field.
+			// For example "Path: 4.4.0/Recommended" means that the package is located in
+			// "latest/src/contrib/4.4.0/Recommended/" subdirectory. By default, we want to avoid
+			// these kinds of packages and prefer to download them from "latest/src/contrib/",
+			// so they're stored separately.
+			if allPackages.PathPackages == nil {
+				allPackages.PathPackages = make(map[string][]PackageDescription)
+			}
+			allPackages.PathPackages[path] = append(allPackages.PathPackages[path], packageDescription)
+			continue
+		}
+		allPackages.Packages = append(allPackages.Packages, packageDescription)
 	}
 	return allPackages
 }
 
+// ParseDCF reads a string in Debian Control File format (used by DESCRIPTION files and
+// PACKAGES file entries) and returns a map from field names to field values. Continuation lines
+// (starting with whitespace) are trimmed and joined with the preceding lines with a newline.
+// Contrary to YAML parsing, this can be used for free-text fields such as Title or Description.
+func ParseDCF(content string) map[string]string {
+	fields := make(map[string]string)
+	var currentField string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if currentField == "" {
+				continue
+			}
+			if fields[currentField] != "" {
+				fields[currentField] += "\n"
+			}
+			fields[currentField] += strings.TrimSpace(line)
+			continue
+		}
+		fieldName, fieldValue, found := strings.Cut(line, ":")
+		if !found {
+			currentField = ""
+			continue
+		}
+		currentField = fieldName
+		fields[currentField] = strings.TrimSpace(fieldValue)
+	}
+	return fields
+}
+
 // ProcessDescription reads a string containing DESCRIPTION file and returns a structure
 // with those fields/properties that are required for further processing.
 func ProcessDescription(description DescriptionFile, allPackages *[]PackageDescription) {
@@ -95,6 +132,7 @@ func ProcessDescription(description DescriptionFile, allPackages *[]PackageDescr
 			packageMap["Package"], packageMap["Version"], description.PackageSource, "", packageDependencies,
 			description.RemoteType, description.RemoteHost, description.RemoteUsername, description.RemoteRepo,
 			description.RemoteSubdir, description.RemoteRef, description.RemoteSha, []string{}, "",
+			ParseDCF(description.Contents),
 		},
 	)
 }
@@ -103,20 +141,16 @@ func ProcessDescription(description DescriptionFile, allPackages *[]PackageDescr
 // package from PACKAGES file (if isDescription is false), or the whole contents of DESCRIPTION file
 // (if isDescription is true). Removes newlines occurring within filtered fields (which are
 // predominantly fields containing lists of package dependencies). Also removes fields which are not
-// required for further processing.
+// required for further processing. In case of PACKAGES file, the "Path:" field is retained as well.
 func CleanDescriptionOrPackagesEntry(description string, isDescription bool) string {
 	lines := strings.Split(description, "\n")
 	filterFields := []string{"Package:", "Version:", "Depends:", "Imports:", "Suggests:", "LinkingTo:"}
+	if !isDescription {
+		filterFields = append(filterFields, "Path:")
+	}
 	outputContent := ""
 	processingFilteredField := false
 	for _, line := range lines {
-		if strings.HasPrefix(line, "Path:") && !isDescription {
-			// This means that the package is located in a subdirectory mentioned in this field.
-			// For example "Path: 4.4.0/Recommended" means that the package is located in
-			// "latest/src/contrib/4.4.0/Recommended/" subdirectory. We want to avoid these kinds of
-			// packages and prefer to download them from "latest/src/contrib/".
-			return ""
-		}
 		filteredFieldFound := false
 		// Check if we start processing any of the filtered fields.
 		for _, field := range filterFields {
@@ -139,6 +173,19 @@ func CleanDescriptionOrPackagesEntry(description string, isDescription bool) str
 	return outputContent
 }
 
+// GetDependenciesFromFields returns the list of package dependencies based on the DESCRIPTION
+// fields read by ParseDCF.
+func GetDependenciesFromFields(fields map[string]string) []Dependency {
+	packageMap := make(map[string]string)
+	for k, v := range fields {
+		// Dependency lists can span multiple lines.
+		packageMap[k] = strings.ReplaceAll(v, "\n", " ")
+	}
+	var packageDependencies []Dependency
+	ProcessDependencyFields(packageMap, &packageDependencies)
+	return packageDependencies
+}
+
 func splitPackageName(r rune) bool {
 	return r == ' ' || r == '('
 }
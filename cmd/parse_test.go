@@ -43,6 +43,14 @@ func Test_ProcessPackagesFile(t *testing.T) {
 						},
 					},
 					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"Depends":          "R (>= 2.15.0)",
+						"License":          "GPL (>= 2)",
+						"MD5sum":           "aaa333444555666777",
+						"NeedsCompilation": "no",
+						"Package":          "somePackage1",
+						"Version":          "1.0.0",
+					},
 				},
 				{
 					"somePackage2",
@@ -69,6 +77,15 @@ func Test_ProcessPackagesFile(t *testing.T) {
 						},
 					},
 					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"Depends":          "R (>= 3.6.0)",
+						"Imports":          "magrittr, dplyr",
+						"License":          "GPL-3",
+						"MD5sum":           "bbb222333444555666",
+						"NeedsCompilation": "no",
+						"Package":          "somePackage2",
+						"Version":          "2.0.0",
+					},
 				},
 				{
 					"somePackage3",
@@ -107,6 +124,16 @@ func Test_ProcessPackagesFile(t *testing.T) {
 						},
 					},
 					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"Depends":          "R (>= 3.1.0)",
+						"Imports":          "ggplot2 (>= 3.1.0), shiny (>= 1.3.1),",
+						"License":          "GPL-3",
+						"MD5sum":           "ccc000111222333444",
+						"NeedsCompilation": "no",
+						"Package":          "somePackage3",
+						"Suggests":         "rmarkdown (>= 1.13), knitr (>= 1.22)",
+						"Version":          "0.0.1",
+					},
 				},
 				{
 					"somePackage4",
@@ -151,6 +178,43 @@ func Test_ProcessPackagesFile(t *testing.T) {
 						},
 					},
 					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"License":          "GPL-3",
+						"MD5sum":           "aaabbbcccdddeeefff",
+						"NeedsCompilation": "no",
+						"Package":          "somePackage4",
+						"Suggests":         "testthat (>= 3.0.0), ggplot2 (>= 3.4.0), knitr\n(>= 1.30), mockery (>= 0.4.2), rmarkdown (>= 2.6), roxygen2 (>=\n7.1.0)",
+						"Version":          "0.2",
+					},
+				},
+			},
+			map[string][]PackageDescription{
+				"4.4.0/Recommended": {
+					{
+						"skippedPackage",
+						"5.0.0",
+						"", "",
+						[]Dependency{
+							{"Depends", "R", ">=", "3.6.0"},
+							{"Imports", "grDevices", "", ""},
+							{"Imports", "graphics", "", ""},
+							{"Imports", "grid", "", ""},
+							{"Imports", "lattice", "", ""},
+							{"Imports", "stats", "", ""},
+							{"Imports", "utils", "", ""},
+						},
+						"", "", "", "", "", "", "", []string{}, "",
+						map[string]string{
+							"Depends":          "R (>= 3.6.0)",
+							"Imports":          "grDevices, graphics, grid, lattice, stats, utils",
+							"License":          "GPL-3",
+							"MD5sum":           "aaabbbccc999888777",
+							"NeedsCompilation": "no",
+							"Package":          "skippedPackage",
+							"Path":             "4.4.0/Recommended",
+							"Version":          "5.0.0",
+						},
+					},
 				},
 			},
 		},
@@ -279,6 +343,22 @@ func Test_ParseDescriptionFileList(t *testing.T) {
 					},
 				},
 				"", "", "", "", "", "", "", []string{}, "",
+				map[string]string{
+					"Date":            "2023-10-13",
+					"Depends":         "R(>= 4.0),\nshiny (>= 1.7.0)",
+					"Description":     "This is a multiline string\nrepresenting package description.",
+					"Encoding":        "UTF-8",
+					"Imports":         "checkmate,\nlifecycle,\nlogger (>= 0.2.0), magrittr,rlang,shinyjs,\nrmarkdown (>=\n0.1.1),\nMultiAssayExperiment\n(>= 0.2.0),\nyaml (>= 0.4.0),\nutils",
+					"Language":        "en-US",
+					"License":         "Apache License 2.0",
+					"Package":         "my.awesome.package",
+					"RoxygenNote":     "7.2.3",
+					"Suggests":        "covr,\ndplyr, knitr,\ntestthat (>= 3.1.5),\nwithr",
+					"Title":           "Package title",
+					"Type":            "Package",
+					"Version":         "0.14.0.9012",
+					"VignetteBuilder": "knitr",
+				},
 			},
 			{
 				"my.awesome.package.2",
@@ -396,7 +476,94 @@ func Test_ParseDescriptionFileList(t *testing.T) {
 					},
 				},
 				"", "", "", "", "", "", "", []string{}, "",
+				map[string]string{
+					"Date":        "2023-10-18",
+					"Depends":     "R (>= 3.6),\nrtables (> 0.6.4)",
+					"Description": "A package description",
+					"Encoding":    "UTF-8",
+					"Imports":     "dplyr,\nforcats (>= 1.0.0), formatters (>= 0.5.3),\nggplot2(>= 3.4.0),\nstats,\nsurvival (>=\n3.2-13),\ntibble, tidyr, utils",
+					"Language":    "en-US",
+					"License":     "Apache License 2.0",
+					"Package":     "my.awesome.package.2",
+					"Suggests":    "knitr, lattice,\nlubridate,\nrmarkdown, stringr,\ntestthat\n(>= 3.0),\nvdiffr (>= 1.0.0)",
+					"Title":       "Package title",
+					"Version":     "0.9.1.9013",
+				},
 			},
 		},
 	)
 }
+
+func Test_ProcessPackagesFileBinary(t *testing.T) {
+	byteValue, err := os.ReadFile("testdata/PACKAGES_binary")
+	checkError(err)
+	allPackages := ProcessPackagesFile(string(byteValue))
+	assert.Equal(t, allPackages,
+		PackagesFile{
+			[]PackageDescription{
+				{
+					"binaryPackage1",
+					"1.0.0",
+					"", "",
+					[]Dependency{
+						{"Depends", "R", ">=", "3.5.0"},
+					},
+					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"Archs":            "i386, x64",
+						"Built":            "R 4.4.1; x86_64-w64-mingw32; 2024-07-01 10:00:00 UTC; windows",
+						"Depends":          "R (>= 3.5.0)",
+						"License":          "GPL-3",
+						"NeedsCompilation": "yes",
+						"Package":          "binaryPackage1",
+						"Version":          "1.0.0",
+					},
+				},
+				{
+					"binaryPackage2",
+					"0.3.1",
+					"", "",
+					nil,
+					"", "", "", "", "", "", "", []string{}, "",
+					map[string]string{
+						"Built":            "R 4.4.0; ; 2024-06-15 08:00:00 UTC; windows",
+						"License":          "MIT + file LICENSE",
+						"NeedsCompilation": "no",
+						"OS_type":          "windows",
+						"Package":          "binaryPackage2",
+						"Version":          "0.3.1",
+					},
+				},
+			},
+			nil,
+		},
+	)
+}
+
+func Test_ParseDCF(t *testing.T) {
+	fields := ParseDCF(
+		"Package: somePackage\r\nTitle: Some Title\r\nDescription: First line\r\n    second line.\r\n" +
+			"Imports:\n\tdplyr,\n    tidyr\n\nVersion: 1.0\n",
+	)
+	assert.Equal(t, fields, map[string]string{
+		"Package":     "somePackage",
+		"Title":       "Some Title",
+		"Description": "First line\nsecond line.",
+		"Imports":     "dplyr,\ntidyr",
+		"Version":     "1.0",
+	})
+}
+
+func Test_GetDependenciesFromFields(t *testing.T) {
+	dependencies := GetDependenciesFromFields(map[string]string{
+		"Package": "somePackage",
+		"Depends": "R (>= 3.6),\nrtables (> 0.6.4)",
+		"Imports": "survival (>=\n3.2-13),\ntibble",
+	})
+	assert.Equal(t, dependencies, []Dependency{
+		{"Depends", "R", ">=", "3.6"},
+		{"Depends", "rtables", ">", "0.6.4"},
+		{"Imports", "survival", ">=", "3.2-13"},
+		{"Imports", "tibble", "", ""},
+	})
+}
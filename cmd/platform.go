@@ -0,0 +1,179 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+)
+
+// Fields of PACKAGES file entries describing the platform for which the package has been built.
+var platformFields = []string{"OS_type", "Built", "Archs"}
+
+// ParseTargetPlatform processes the target platform in the format '<os>/<arch>' (e.g. 'macos/arm64')
+// where the architecture is optional. It returns the operating system and the normalized architecture.
+func ParseTargetPlatform(targetPlatform string) (string, string) {
+	if targetPlatform == "" {
+		return "", ""
+	}
+	platform := strings.Split(strings.ToLower(targetPlatform), "/")
+	targetOS := platform[0]
+	if !stringInSlice(targetOS, []string{"linux", "windows", "macos"}) {
+		log.Fatal("Unknown operating system in --targetPlatform: ", targetPlatform,
+			". Please use one of: linux, windows, macos, optionally followed by '/<arch>'.")
+	}
+	var targetArch string
+	if len(platform) > 1 {
+		targetArch = NormalizeArchitecture(platform[1])
+	}
+	return targetOS, targetArch
+}
+
+// NormalizeArchitecture converts different names of the same CPU architecture
+// (as used by R, Windows and macOS) to a common name.
+func NormalizeArchitecture(arch string) string {
+	arch = strings.ToLower(strings.TrimSpace(arch))
+	switch arch {
+	case "x64", "amd64", "x86_64":
+		return "x86_64"
+	case "arm64", "aarch64":
+		return "aarch64"
+	case "i386", "i686", "x86":
+		return "i386"
+	}
+	return arch
+}
+
+// CheckIfPackageMatchesPlatform checks whether the PACKAGES file entry can be installed on targetOS
+// with targetArch CPU architecture and R version rVersion. It uses the 'OS_type' field, as well as
+// the 'Archs' and 'Built' fields present in the PACKAGES files of repositories with binary packages.
+// If the package doesn't match the platform, the reason is returned as the second value.
+func CheckIfPackageMatchesPlatform(p PackageDescription, targetOS string, targetArch string,
+	rVersion string) (bool, string) {
+	if osType := p.Fields["OS_type"]; !CheckIfOSTypeMatches(osType, targetOS) {
+		return false, "OS_type: " + osType
+	}
+	if archs, ok := p.Fields["Archs"]; ok && !CheckIfArchsMatch(archs, targetArch) {
+		return false, "Archs: " + archs
+	}
+	if built, ok := p.Fields["Built"]; ok && !CheckIfBuiltMatches(built, targetOS, targetArch, rVersion) {
+		return false, "Built: " + built
+	}
+	return true, ""
+}
+
+// CheckIfOSTypeMatches checks whether the 'OS_type' field of a package (empty, 'unix' or 'windows')
+// allows the package to be installed on targetOS.
+func CheckIfOSTypeMatches(osType string, targetOS string) bool {
+	switch osType {
+	case "windows":
+		return targetOS == "" || targetOS == "windows"
+	case "unix":
+		return targetOS != "windows"
+	}
+	return true
+}
+
+// CheckIfArchsMatch checks whether the comma-separated list of CPU architectures from the 'Archs' field
+// of a package contains targetArch.
+func CheckIfArchsMatch(archs string, targetArch string) bool {
+	if targetArch == "" {
+		return true
+	}
+	for _, a := range strings.Split(archs, ",") {
+		if NormalizeArchitecture(a) == targetArch {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBuiltOS returns the operating system (as used in --targetPlatform) for which a binary package has been built,
+// based on the platform triplet (e.g. 'aarch64-apple-darwin20' or 'x86_64-w64-mingw32') from the 'Built' field,
+// or, if the triplet is empty, on the last component of the field ('unix' or 'windows').
+// An empty string is returned if the operating system can't be determined, or if it can be either linux or macos.
+func GetBuiltOS(triplet string, osType string) string {
+	switch {
+	case strings.Contains(triplet, "darwin"):
+		return "macos"
+	case strings.Contains(triplet, "mingw32"):
+		return "windows"
+	case strings.Contains(triplet, "linux"):
+		return "linux"
+	case triplet == "" && osType == "windows":
+		return "windows"
+	}
+	return ""
+}
+
+// CheckIfBuiltMatches checks whether the package built according to the 'Built' field can be installed
+// on targetOS with targetArch CPU architecture and R version rVersion.
+// The 'Built' field has the following format: 'R 4.4.1; aarch64-apple-darwin20; <date>; unix'.
+// The second component (platform triplet) is empty for packages without compiled code.
+func CheckIfBuiltMatches(built string, targetOS string, targetArch string, rVersion string) bool {
+	builtComponents := strings.Split(built, ";")
+	builtRVersion := strings.FieldsFunc(strings.TrimPrefix(builtComponents[0], "R "), splitVersion)
+	targetRVersion := strings.FieldsFunc(rVersion, splitVersion)
+	if len(builtRVersion) >= 2 && len(targetRVersion) >= 2 &&
+		(builtRVersion[0] != targetRVersion[0] || builtRVersion[1] != targetRVersion[1]) {
+		return false
+	}
+	if len(builtComponents) < 2 {
+		return true
+	}
+	triplet := strings.TrimSpace(builtComponents[1])
+	if builtArch := strings.Split(triplet, "-")[0]; builtArch != "" && targetArch != "" &&
+		NormalizeArchitecture(builtArch) != targetArch {
+		return false
+	}
+	var builtOSType string
+	if len(builtComponents) > 3 {
+		builtOSType = strings.TrimSpace(builtComponents[3])
+	}
+	if builtOS := GetBuiltOS(triplet, builtOSType); builtOS != "" && targetOS != "" {
+		return builtOS == targetOS
+	}
+	return builtOSType != "unix" || targetOS != "windows"
+}
+
+// FilterPackagesFiles removes from packagesFiles the entries of packages which cannot be installed on
+// targetPlatform with R version rVersion, so that they are not taken into account during the resolution
+// of dependencies.
+func FilterPackagesFiles(packagesFiles map[string]PackagesFile, targetPlatform string, rVersion string) {
+	targetOS, targetArch := ParseTargetPlatform(targetPlatform)
+	if targetOS == "" && rVersion == "" {
+		return
+	}
+	filterPackages := func(repository string, packages []PackageDescription) []PackageDescription {
+		var filteredPackages []PackageDescription
+		for _, p := range packages {
+			if match, reason := CheckIfPackageMatchesPlatform(p, targetOS, targetArch, rVersion); !match {
+				log.Debug("Skipping ", p.Package, " from ", repository, " as it doesn't match the target platform (",
+					reason, ").")
+				continue
+			}
+			filteredPackages = append(filteredPackages, p)
+		}
+		return filteredPackages
+	}
+	for repository, packagesFile := range packagesFiles {
+		packagesFile.Packages = filterPackages(repository, packagesFile.Packages)
+		for path, packages := range packagesFile.PathPackages {
+			packagesFile.PathPackages[path] = filterPackages(repository, packages)
+		}
+		packagesFiles[repository] = packagesFile
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseTargetPlatform(t *testing.T) {
+	targetOS, targetArch := ParseTargetPlatform("macos/arm64")
+	assert.Equal(t, targetOS, "macos")
+	assert.Equal(t, targetArch, "aarch64")
+	targetOS, targetArch = ParseTargetPlatform("Windows/x64")
+	assert.Equal(t, targetOS, "windows")
+	assert.Equal(t, targetArch, "x86_64")
+	targetOS, targetArch = ParseTargetPlatform("linux")
+	assert.Equal(t, targetOS, "linux")
+	assert.Equal(t, targetArch, "")
+}
+
+func Test_CheckIfPackageMatchesPlatform(t *testing.T) {
+	windowsOnly := PackageDescription{Package: "package1", Fields: map[string]string{"OS_type": "windows"}}
+	match, _ := CheckIfPackageMatchesPlatform(windowsOnly, "linux", "", "")
+	assert.False(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(windowsOnly, "windows", "", "")
+	assert.True(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(windowsOnly, "", "", "")
+	assert.True(t, match)
+
+	unixOnly := PackageDescription{Package: "package2", Fields: map[string]string{"OS_type": "unix"}}
+	match, _ = CheckIfPackageMatchesPlatform(unixOnly, "windows", "", "")
+	assert.False(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(unixOnly, "macos", "", "")
+	assert.True(t, match)
+
+	windowsBinary := PackageDescription{Package: "package3", Fields: map[string]string{
+		"Archs": "i386, x64",
+		"Built": "R 4.4.1; x86_64-w64-mingw32; 2024-07-01 10:00:00 UTC; windows",
+	}}
+	match, _ = CheckIfPackageMatchesPlatform(windowsBinary, "windows", "x86_64", "4.4")
+	assert.True(t, match)
+	match, reason := CheckIfPackageMatchesPlatform(windowsBinary, "windows", "x86_64", "4.3.2")
+	assert.False(t, match)
+	assert.Equal(t, reason, "Built: R 4.4.1; x86_64-w64-mingw32; 2024-07-01 10:00:00 UTC; windows")
+	match, reason = CheckIfPackageMatchesPlatform(windowsBinary, "windows", "aarch64", "4.4")
+	assert.False(t, match)
+	assert.Equal(t, reason, "Archs: i386, x64")
+
+	macOSBinary := PackageDescription{Package: "package4", Fields: map[string]string{
+		"Built": "R 4.4.0; aarch64-apple-darwin20; 2024-05-01 10:00:00 UTC; unix",
+	}}
+	match, _ = CheckIfPackageMatchesPlatform(macOSBinary, "macos", "aarch64", "4.4.1")
+	assert.True(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(macOSBinary, "macos", "x86_64", "4.4.1")
+	assert.False(t, match)
+	// Binaries built for other operating systems don't match, even if the architecture matches or isn't set.
+	match, reason = CheckIfPackageMatchesPlatform(macOSBinary, "linux", "aarch64", "4.4.1")
+	assert.False(t, match)
+	assert.Equal(t, reason, "Built: R 4.4.0; aarch64-apple-darwin20; 2024-05-01 10:00:00 UTC; unix")
+	match, _ = CheckIfPackageMatchesPlatform(windowsBinary, "linux", "", "4.4")
+	assert.False(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(windowsBinary, "", "", "4.4")
+	assert.True(t, match)
+	linuxBinary := PackageDescription{Package: "package6", Fields: map[string]string{
+		"Built": "R 4.4.1; x86_64-pc-linux-gnu; 2024-07-01 10:00:00 UTC; unix",
+	}}
+	match, _ = CheckIfPackageMatchesPlatform(linuxBinary, "linux", "", "4.4")
+	assert.True(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(linuxBinary, "macos", "x86_64", "4.4")
+	assert.False(t, match)
+
+	noCompiledCode := PackageDescription{Package: "package5", Fields: map[string]string{
+		"Built": "R 4.4.0; ; 2024-05-01 10:00:00 UTC; unix",
+	}}
+	match, _ = CheckIfPackageMatchesPlatform(noCompiledCode, "macos", "x86_64", "4.4")
+	assert.True(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(noCompiledCode, "windows", "", "4.4")
+	assert.False(t, match)
+	noCompiledCodeWindows := PackageDescription{Package: "package7", Fields: map[string]string{
+		"Built": "R 4.4.0; ; 2024-05-01 10:00:00 UTC; windows",
+	}}
+	match, _ = CheckIfPackageMatchesPlatform(noCompiledCodeWindows, "linux", "", "4.4")
+	assert.False(t, match)
+	match, _ = CheckIfPackageMatchesPlatform(noCompiledCodeWindows, "windows", "x86_64", "4.4")
+	assert.True(t, match)
+}
+
+func Test_GetBuiltOS(t *testing.T) {
+	assert.Equal(t, GetBuiltOS("aarch64-apple-darwin20", "unix"), "macos")
+	assert.Equal(t, GetBuiltOS("x86_64-w64-mingw32", "windows"), "windows")
+	assert.Equal(t, GetBuiltOS("x86_64-pc-linux-gnu", "unix"), "linux")
+	assert.Equal(t, GetBuiltOS("", "windows"), "windows")
+	assert.Equal(t, GetBuiltOS("", "unix"), "")
+}
+
+func Test_FilterPackagesFiles(t *testing.T) {
+	packagesFiles := map[string]PackagesFile{
+		"https://repo1.example.com/ExampleRepo1": {
+			[]PackageDescription{
+				{Package: "package1", Fields: map[string]string{"OS_type": "windows"}},
+				{Package: "package2"},
+				{Package: "package3", Fields: map[string]string{"OS_type": "unix"}},
+			},
+			map[string][]PackageDescription{
+				"4.4.0/Recommended": {
+					{Package: "package4", Fields: map[string]string{"OS_type": "windows"}},
+				},
+			},
+		},
+	}
+	FilterPackagesFiles(packagesFiles, "linux", "")
+	assert.Equal(t, packagesFiles["https://repo1.example.com/ExampleRepo1"].Packages, []PackageDescription{
+		{Package: "package2"},
+		{Package: "package3", Fields: map[string]string{"OS_type": "unix"}},
+	})
+	assert.Empty(t, packagesFiles["https://repo1.example.com/ExampleRepo1"].PathPackages["4.4.0/Recommended"])
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var rootPackages string
+
+func newPruneCommand() *cobra.Command {
+	pruneCmd := &cobra.Command{
+		Use:   "prune <renv.lock>",
+		Short: "Remove packages not required by the root packages from renv.lock",
+		Long: `Remove from renv.lock the packages which are not required (directly or indirectly)
+by the root packages, and save the result to --outputRenvLock. The root packages are provided
+with --rootPackages, and/or as the input packages (--inputPackageList or inputPackages in the
+configuration file) together with their dependencies.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			setLogLevel()
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				log.Fatal("Could not read lockfile ", args[0], ": ", err)
+			}
+			renvLock := ReadRenvLock(args[0])
+			downloadFileFunction := CacheDownloads(DownloadTextFile)
+			roots := GetRootPackages(rootPackages, downloadFileFunction)
+			if len(roots) == 0 {
+				log.Fatal("No root packages specified. Please use the --rootPackages flag or the input packages.")
+			}
+			dependencies := GetLockedPackageDependencies(renvLock, GetLockfileRecords(content), downloadFileFunction)
+			removedPackages, unknownDependencies := PruneRenvLock(&renvLock, roots, dependencies)
+			if len(unknownDependencies) > 0 {
+				log.Fatal("Could not determine the dependencies of: ", strings.Join(unknownDependencies, ", "),
+					". No packages have been removed.")
+			}
+			if len(removedPackages) == 0 {
+				fmt.Println("No packages removed.")
+			} else {
+				fmt.Println("Removed packages:")
+				for _, p := range removedPackages {
+					fmt.Println("  " + p)
+				}
+			}
+			writeJSON(outputRenvLock, GetUpdatedLockfileContents(args[0], renvLock, lockfileVersion))
+		},
+	}
+	pruneCmd.Flags().StringVarP(&rootPackages, "rootPackages", "", "",
+		"Comma-separated list of the names of root packages.")
+	return pruneCmd
+}
+
+// GetRootPackages returns the names of root packages: the packages from the comma-separated
+// rootPackageList, and the input packages (downloaded with downloadFileFunction) together with
+// their hard dependencies.
+func GetRootPackages(rootPackageList string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) []string {
+	var roots []string
+	for _, p := range strings.Split(rootPackageList, ",") {
+		if p = strings.TrimSpace(p); p != "" && !stringInSlice(p, roots) {
+			roots = append(roots, p)
+		}
+	}
+	packageList := inputPackages
+	if inputPackageList != "" {
+		packageList = strings.Split(inputPackageList, ",")
+	}
+	for _, p := range ParseDescriptionFileList(DownloadDescriptionFiles(packageList, downloadFileFunction)) {
+		for _, name := range append([]string{p.Package}, GetRequirements(p.Dependencies)...) {
+			if !stringInSlice(name, roots) {
+				roots = append(roots, name)
+			}
+		}
+	}
+	return roots
+}
+
+// GetLockedPackageDependencies returns a map from the names of packages in renvLock to the names of their
+// hard dependencies. The dependencies are read from the package records (Depends, Imports, LinkingTo
+// or Requirements fields). For records without this information, the dependencies are read from the PACKAGES
+// files of the repositories defined in the lockfile, or from the DESCRIPTION files in git repositories,
+// downloaded with downloadFileFunction. The packages whose dependencies couldn't be determined
+// are not present in the returned map.
+func GetLockedPackageDependencies(renvLock RenvLock, records map[string]LockfileRecord,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) map[string][]string {
+	dependencies := make(map[string][]string)
+	var packagesFiles map[string]PackagesFile
+	var repositoryNames []string
+	for k, p := range renvLock.Packages {
+		record := records[k]
+		var hasDependencies bool
+		for _, fieldName := range []string{depends, imports, linkingTo, "Requirements"} {
+			if _, ok := record.Get(fieldName); ok {
+				hasDependencies = true
+			}
+		}
+		if hasDependencies {
+			dependencies[k] = GetRequirements(GetRecordDependencies(record, p))
+			continue
+		}
+		if p.Source == GitHub || p.Source == GitLab {
+			descriptionURL, token := GetDescriptionFileURL(p, p.RemoteSha)
+			_, descriptionContent, err := downloadFileFunction(descriptionURL, token)
+			if err != nil {
+				log.Error("Could not download ", descriptionURL, " to read the dependencies of ", k, ".")
+				continue
+			}
+			dependencies[k] = GetRequirements(GetDependenciesFromFields(ParseDCF(descriptionContent)))
+			continue
+		}
+		if packagesFiles == nil {
+			packagesFiles = make(map[string]PackagesFile)
+			for _, r := range renvLock.R.Repositories {
+				repositoryNames = append(repositoryNames, r.Name)
+				packagesFiles[r.Name] = ProcessPackagesFile(GetPackagesFileContent(r.URL, downloadFileFunction))
+			}
+		}
+		// Prefer the repository from which the package is downloaded.
+		packageRepositoryNames := append([]string{p.Repository}, repositoryNames...)
+		repositoryPackage, repositoryName := FindPackageInRepositories(
+			k, "==", p.Version, packageRepositoryNames, packagesFiles,
+		)
+		if repositoryName == "" {
+			repositoryPackage, repositoryName = FindPackageInRepositories(
+				k, "", "", packageRepositoryNames, packagesFiles,
+			)
+			if repositoryName == "" {
+				log.Error("Could not find the dependencies of ", k, " in any of the repositories.")
+				continue
+			}
+			log.Warn("Version ", p.Version, " of ", k, " not found in the repositories. The dependencies of ",
+				k, " are read from version ", repositoryPackage.Version, " instead.")
+		}
+		dependencies[k] = GetRequirements(repositoryPackage.Dependencies)
+	}
+	return dependencies
+}
+
+// PruneRenvLock removes from renvLock the packages which are not reachable from the rootPackages
+// according to the dependencies map. It returns the sorted list of removed packages. If the dependencies
+// of any reachable package are unknown (i.e. it's not present in the dependencies map), no packages are removed,
+// as they might be required by that package, and the sorted list of such packages is returned as the second value.
+func PruneRenvLock(renvLock *RenvLock, rootPackages []string,
+	dependencies map[string][]string) ([]string, []string) {
+	reachable := make(map[string]bool)
+	queue := append([]string{}, rootPackages...)
+	for len(queue) > 0 {
+		packageName := queue[0]
+		queue = queue[1:]
+		if reachable[packageName] {
+			continue
+		}
+		reachable[packageName] = true
+		queue = append(queue, dependencies[packageName]...)
+	}
+	for _, root := range rootPackages {
+		if _, ok := renvLock.Packages[root]; !ok {
+			log.Debug("Root package ", root, " is not present in the lockfile.")
+		}
+	}
+	unknownDependencies := []string{}
+	for k := range renvLock.Packages {
+		if _, ok := dependencies[k]; reachable[k] && !ok {
+			unknownDependencies = append(unknownDependencies, k)
+		}
+	}
+	if len(unknownDependencies) > 0 {
+		sort.Strings(unknownDependencies)
+		return []string{}, unknownDependencies
+	}
+	removedPackages := []string{}
+	for k := range renvLock.Packages {
+		if !reachable[k] {
+			removedPackages = append(removedPackages, k)
+			delete(renvLock.Packages, k)
+		}
+	}
+	sort.Strings(removedPackages)
+	return removedPackages, unknownDependencies
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockedDownloadPruneFile(url string, _ map[string]string) (int64, string, error) {
+	switch url {
+	case "https://repo1.example.com/src/contrib/PACKAGES":
+		return 0, "Package: package3\nVersion: 3.0.0\nImports: package4, utils\n\n" +
+			"Package: package4\nVersion: 4.0.0\n\nPackage: package8\nVersion: 8.1.0\nImports: package4\n", nil
+	case "https://raw.githubusercontent.com/org1/package5/aaa111/DESCRIPTION":
+		return 0, "Package: package5\nVersion: 5.0.0\nDepends: R (>= 4.0)\nLinkingTo: package6\n", nil
+	}
+	return 0, "", errors.New("Received status code 404")
+}
+
+func Test_GetLockedPackageDependencies(t *testing.T) {
+	content := []byte(`{
+  "R": {"Version": "4.3.2", "Repositories": [{"Name": "Repo1", "URL": "https://repo1.example.com"}]},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "Repo1",
+      "Imports": ["package2 (>= 2.0)", "stats"], "Suggests": ["package7"]},
+    "package2": {"Package": "package2", "Version": "2.0.0", "Source": "Repository", "Repository": "Repo1",
+      "Requirements": ["package3"]},
+    "package3": {"Package": "package3", "Version": "3.0.0", "Source": "Repository", "Repository": "Repo1"},
+    "package4": {"Package": "package4", "Version": "4.0.0", "Source": "Repository", "Repository": "Repo1"},
+    "package5": {"Package": "package5", "Version": "5.0.0", "Source": "GitHub", "RemoteType": "github",
+      "RemoteHost": "api.github.com", "RemoteUsername": "org1", "RemoteRepo": "package5",
+      "RemoteRef": "main", "RemoteSha": "aaa111"},
+    "package8": {"Package": "package8", "Version": "8.0.0", "Source": "Repository", "Repository": "Repo1"},
+    "package9": {"Package": "package9", "Version": "9.0.0", "Source": "Repository", "Repository": "Repo1"}
+  }
+}`)
+	var renvLock RenvLock
+	err := json.Unmarshal(content, &renvLock)
+	assert.Nil(t, err)
+	dependencies := GetLockedPackageDependencies(renvLock, GetLockfileRecords(content), mockedDownloadPruneFile)
+	assert.Equal(t, dependencies, map[string][]string{
+		"package1": {"package2"},
+		"package2": {"package3"},
+		"package3": {"package4"},
+		"package4": {},
+		"package5": {"package6"},
+		// Dependencies read from a different version than the locked one.
+		"package8": {"package4"},
+		// package9 is not present in any repository.
+	})
+}
+
+func Test_PruneRenvLock(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{},
+	}
+	for _, k := range []string{"package1", "package2", "package3", "package4", "package5", "package6"} {
+		renvLock.Packages[k] = PackageDescription{
+			k, "1.0.0", "Repository", "Repo1", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		}
+	}
+	// The dependencies of package3 are unknown, so no packages can be removed.
+	removedPackages, unknownDependencies := PruneRenvLock(&renvLock, []string{"package1", "package8"},
+		map[string][]string{
+			"package1": {"package2"},
+			"package2": {"package3"},
+			"package4": {"package5"},
+			"package5": {"package1"},
+		},
+	)
+	assert.Equal(t, removedPackages, []string{})
+	assert.Equal(t, unknownDependencies, []string{"package3"})
+	assert.Len(t, renvLock.Packages, 6)
+	removedPackages, unknownDependencies = PruneRenvLock(&renvLock, []string{"package1", "package8"},
+		map[string][]string{
+			"package1": {"package2"},
+			"package2": {"package3"},
+			"package3": {},
+			"package4": {"package5"},
+			"package5": {"package1"},
+		},
+	)
+	assert.Equal(t, removedPackages, []string{"package4", "package5", "package6"})
+	assert.Equal(t, unknownDependencies, []string{})
+	assert.Len(t, renvLock.Packages, 3)
+}
@@ -18,25 +18,52 @@ package cmd
 
 import (
 	"encoding/json"
+	"net/url"
 	"os"
 	"regexp"
 	"sort"
 	"strings"
 
 	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
-	yaml "gopkg.in/yaml.v3"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 const GitHub = "GitHub"
 const GitLab = "GitLab"
 const https = "https://"
+const gitHubHost = "github.com"
+const gitHubAPIHost = "api.github.com"
+const gitHubEnterpriseAPIPath = "/api/v3"
+
+const gitUpdatePolicyTrackRef = "track-ref"
+const gitUpdatePolicyDefaultBranch = "default-branch"
+const gitUpdatePolicyLatestTag = "latest-tag"
+
+const branchRefPrefix = "refs/heads/"
+const tagRefPrefix = "refs/tags/"
+
+// Suffix of the references pointing to the commits to which annotated tags point.
+const peeledRefSuffix = "^{}"
+
+// Tags such as 'v1.2.3' or '1.2-3' are treated as package versions.
+var versionTagRegexp = regexp.MustCompile(`^v?(\d+([.-]\d+)*)$`)
+
+// Git references such as 'a1b2c3d' are treated as (possibly abbreviated) commit SHAs.
+var commitShaRegexp = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
 
 // GenerateRenvLock generates renv.lock file structure which can be then saved as a JSON file.
 // It uses a list of package data created by ConstructOutputPackageList, and the map of
 // package repositories containing the packages.
-func GenerateRenvLock(packageList []PackageDescription, repositoryMap map[string]string) RenvLock {
+func GenerateRenvLock(packageList []PackageDescription, repositoryMap map[string]string,
+	rVersion string, bioconductorVersion string) RenvLock {
 	var outputRenvLock RenvLock
+	outputRenvLock.R.Version = rVersion
+	if bioconductorVersion != "" {
+		outputRenvLock.Bioconductor = &RenvLockBioconductor{bioconductorVersion}
+	}
 	outputRenvLock.Packages = make(map[string]PackageDescription)
 	for _, p := range packageList {
 		// Filter out package entries that were intentionally cleared during the process
@@ -47,8 +74,10 @@ func GenerateRenvLock(packageList []PackageDescription, repositoryMap map[string
 		// Replace package repository URL with package repository alias/name.
 		repositoryKey := GetRepositoryKeyByValue(p.Repository, repositoryMap)
 		p.Repository = repositoryKey
+		p.Hash = GetPackageHash(p)
 		outputRenvLock.Packages[p.Package] = p
 	}
+	RemoveUnresolvedRequirements(&outputRenvLock)
 	// As the repository map is not sorted, in order to generate predictable output
 	// we have to process the repository names in sorted order.
 	var repositoryKeys []string
@@ -62,6 +91,52 @@ func GenerateRenvLock(packageList []PackageDescription, repositoryMap map[string
 	return outputRenvLock
 }
 
+// GetBioconductorVersion returns the Bioconductor release which should be saved in the lockfile.
+// If it hasn't been configured, it's derived from the URLs of Bioconductor repositories
+// in repositoryList, such as 'https://bioconductor.org/packages/3.18/bioc'.
+func GetBioconductorVersion(configuredVersion string, repositoryList []string) string {
+	if configuredVersion != "" {
+		return configuredVersion
+	}
+	var bioconductorVersion string
+	re := regexp.MustCompile(`/packages/(\d+\.\d+)/`)
+	for _, repositoryURL := range repositoryList {
+		match := re.FindStringSubmatch(repositoryURL + "/")
+		if match == nil {
+			continue
+		}
+		if bioconductorVersion == "" {
+			bioconductorVersion = match[1]
+		} else if match[1] != bioconductorVersion {
+			log.Warn("Repository ", repositoryURL, " refers to Bioconductor ", match[1], " while Bioconductor ",
+				bioconductorVersion, " will be saved in the lockfile.")
+		}
+	}
+	return bioconductorVersion
+}
+
+// RemoveUnresolvedRequirements removes from the Requirements of packages in renvLock those packages
+// which are not present in renvLock (e.g. because they have been excluded or could not be found),
+// unless they are base or recommended packages which might have been installed together with R.
+// (Base packages are listed in the Requirements of lockfiles written by renv.)
+func RemoveUnresolvedRequirements(renvLock *RenvLock) {
+	for k, p := range renvLock.Packages {
+		if len(p.Requirements) == 0 {
+			continue
+		}
+		requirements := []string{}
+		for _, r := range p.Requirements {
+			if _, ok := renvLock.Packages[r]; ok || CheckIfRecommendedPackage(r) || CheckIfBasePackage(r) {
+				requirements = append(requirements, r)
+			} else {
+				log.Trace("Removing ", r, " from the requirements of ", k, " as it's not present in the lockfile.")
+			}
+		}
+		p.Requirements = requirements
+		renvLock.Packages[k] = p
+	}
+}
+
 // GetRepositoryKeyByValue searches for repository URL in repositoryMap and returns
 // the name (alias) of that repository which will then be used in output renv.lock file.
 func GetRepositoryKeyByValue(repositoryURL string, repositoryMap map[string]string) string {
@@ -91,14 +166,15 @@ func GetPackageRegex(updatedPackages string) string {
 // GetPackageVersionFromDescription reads the DESCRIPTION file located in descriptionFilePath
 // and returns the package version.
 func GetPackageVersionFromDescription(descriptionFilePath string) string {
+	return ReadDescriptionFields(descriptionFilePath)["Version"]
+}
+
+// ReadDescriptionFields reads the DESCRIPTION file from descriptionFilePath and returns
+// a map from the field names to the field values.
+func ReadDescriptionFields(descriptionFilePath string) map[string]string {
 	byteValue, err := os.ReadFile(descriptionFilePath)
 	checkError(err)
-	cleanedDescription := CleanDescriptionOrPackagesEntry(string(byteValue), true)
-	descriptionContents := make(map[string]string)
-	err = yaml.Unmarshal([]byte(cleanedDescription), &descriptionContents)
-	checkError(err)
-	version := descriptionContents["Version"]
-	return version
+	return ParseDCF(string(byteValue))
 }
 
 // GetGitRepositoryURL reads the PackageDescription struct corresponding to a single package
@@ -107,7 +183,7 @@ func GetGitRepositoryURL(p PackageDescription) string {
 	var repoURL string
 	switch p.Source {
 	case GitHub:
-		repoURL = "https://github.com/" + p.RemoteUsername + "/" + p.RemoteRepo
+		repoURL = https + GetGitHubHost(p.RemoteHost) + "/" + p.RemoteUsername + "/" + p.RemoteRepo
 	case GitLab:
 		// The behavior of renv.lock is not standardized in terms of whether GitLab
 		// host address starts with 'https://' or not.
@@ -122,32 +198,61 @@ func GetGitRepositoryURL(p PackageDescription) string {
 	return repoURL
 }
 
-// GetDefaultBranchSha clones the git repository located at repoURL to gitDirectory, using Personal
-// Access Tokens from LOCKSMITH_GITLABTOKEN or LOCKSMITH_GITHUBTOKEN environment variables.
-// It returns the commit SHA of the HEAD of default branch and the name of the default branch,
-// or empty string in case of error.
-func GetDefaultBranchSha(gitDirectory string, repoURL string,
-	environmentCredentialsType string) (string, string) {
+// GetGitHubHost returns the host of the GitHub instance (github.com or a GitHub Enterprise host)
+// based on the RemoteHost field of the package, which contains the API host,
+// such as 'api.github.com' or 'github.example.com/api/v3'.
+func GetGitHubHost(remoteHost string) string {
+	host := strings.TrimSuffix(strings.TrimPrefix(remoteHost, https), "/")
+	if host == "" || host == gitHubAPIHost {
+		return gitHubHost
+	}
+	return strings.TrimSuffix(host, gitHubEnterpriseAPIPath)
+}
+
+// GetGitHubAPIURL returns the URL of the API of the GitHub instance (api.github.com or a GitHub Enterprise host)
+// based on the RemoteHost field of the package.
+func GetGitHubAPIURL(remoteHost string) string {
+	host := GetGitHubHost(remoteHost)
+	if host == gitHubHost {
+		return https + gitHubAPIHost
+	}
+	return https + host + gitHubEnterpriseAPIPath
+}
+
+// GetGitAuth returns the credentials used to access git repositories, based on Personal Access Tokens
+// from LOCKSMITH_GITLABTOKEN or LOCKSMITH_GITHUBTOKEN environment variables.
+func GetGitAuth(environmentCredentialsType string) *githttp.BasicAuth {
+	switch environmentCredentialsType {
+	case GitLab:
+		return &githttp.BasicAuth{
+			Username: "This can be any string.",
+			Password: os.Getenv("LOCKSMITH_GITLABTOKEN"),
+		}
+	case GitHub:
+		return &githttp.BasicAuth{
+			Username: "This can be any string.",
+			Password: os.Getenv("LOCKSMITH_GITHUBTOKEN"),
+		}
+	}
+	return nil
+}
+
+// GetGitRefSha clones the git repository located at repoURL to gitDirectory, checking out the reference
+// refName (e.g. 'refs/heads/release-1.x' or 'refs/tags/v1.0.0'), or the default branch if refName is empty.
+// It returns the commit SHA of the checked out reference and its short name (e.g. 'release-1.x' or 'v1.0.0'),
+// or empty strings in case of error.
+func GetGitRefSha(gitDirectory string, repoURL string, environmentCredentialsType string,
+	refName string) (string, string) {
 	err := os.MkdirAll(gitDirectory, os.ModePerm)
 	checkError(err)
-	var gitCloneOptions *git.CloneOptions
-	switch {
-	case environmentCredentialsType == GitLab:
-		gitCloneOptions = &git.CloneOptions{
-			URL: repoURL,
-			Auth: &githttp.BasicAuth{
-				Username: "This can be any string.",
-				Password: os.Getenv("LOCKSMITH_GITLABTOKEN")},
-			Depth: 1,
-		}
-	case environmentCredentialsType == GitHub:
-		gitCloneOptions = &git.CloneOptions{
-			URL: repoURL,
-			Auth: &githttp.BasicAuth{
-				Username: "This can be any string.",
-				Password: os.Getenv("LOCKSMITH_GITHUBTOKEN")},
-			Depth: 1,
-		}
+	gitCloneOptions := &git.CloneOptions{
+		URL:           repoURL,
+		ReferenceName: plumbing.ReferenceName(refName),
+		SingleBranch:  refName != "",
+		Depth:         1,
+	}
+	if auth := GetGitAuth(environmentCredentialsType); auth != nil {
+		gitCloneOptions.Auth = auth
 	}
 	repository, err := git.PlainClone(gitDirectory, false, gitCloneOptions)
 	if err != nil {
@@ -157,15 +262,219 @@ func GetDefaultBranchSha(gitDirectory string, repoURL string,
 	// Get SHA of repository HEAD.
 	ref, err := repository.Head()
 	checkError(err)
-	return ref.Hash().String(), strings.TrimPrefix(ref.Name().String(), "refs/heads/")
+	if refName == "" {
+		return ref.Hash().String(), ref.Name().Short()
+	}
+	return ref.Hash().String(), plumbing.ReferenceName(refName).Short()
+}
+
+// ListGitRefs lists the references in the git repository located at repoURL, similarly to 'git ls-remote',
+// without cloning the repository. It returns a map from reference names (e.g. 'refs/heads/main'
+// or 'refs/tags/v1.0.0') to commit SHAs (annotated tags are resolved to the commits they point to),
+// and the name of the default branch reference, or an empty map in case of error.
+func ListGitRefs(repoURL string, environmentCredentialsType string) (map[string]string, string) {
+	refShas := make(map[string]string)
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{repoURL}})
+	listOptions := &git.ListOptions{PeelingOption: git.AppendPeeled}
+	if auth := GetGitAuth(environmentCredentialsType); auth != nil {
+		listOptions.Auth = auth
+	}
+	refs, err := remote.List(listOptions)
+	if err != nil {
+		log.Error("Error while listing references of ", repoURL, ": ", err)
+		return refShas, ""
+	}
+	var defaultBranch string
+	peeledRefShas := make(map[string]string)
+	for _, ref := range refs {
+		refName := ref.Name().String()
+		switch {
+		case ref.Type() == plumbing.SymbolicReference:
+			if ref.Name() == plumbing.HEAD {
+				defaultBranch = ref.Target().String()
+			}
+		case strings.HasSuffix(refName, peeledRefSuffix):
+			peeledRefShas[strings.TrimSuffix(refName, peeledRefSuffix)] = ref.Hash().String()
+		default:
+			refShas[refName] = ref.Hash().String()
+		}
+	}
+	// Annotated tags point to tag objects, so use the SHAs of commits they point to.
+	for k, v := range peeledRefShas {
+		refShas[k] = v
+	}
+	return refShas, defaultBranch
+}
+
+// GetDescriptionFileURL returns the URL from which the DESCRIPTION file of the package p can be downloaded
+// at the given commit SHA without cloning the repository, together with the authentication headers.
+// The GitHub token is only sent to github.com, as it's not valid for GitHub Enterprise hosts.
+func GetDescriptionFileURL(p PackageDescription, sha string) (string, map[string]string) {
+	token := make(map[string]string)
+	descriptionPath := "DESCRIPTION"
+	if p.RemoteSubdir != "" {
+		descriptionPath = p.RemoteSubdir + "/" + descriptionPath
+	}
+	if p.Source == GitHub {
+		host := GetGitHubHost(p.RemoteHost)
+		if host != gitHubHost {
+			// GitHub Enterprise doesn't serve raw files from a separate host,
+			// so the file is downloaded with the contents API.
+			token["Accept"] = "application/vnd.github.raw"
+			return GetGitHubAPIURL(p.RemoteHost) + "/repos/" + p.RemoteUsername + "/" + p.RemoteRepo +
+				"/contents/" + descriptionPath + "?ref=" + sha, token
+		}
+		if gitHubToken != "" {
+			token["Authorization"] = "token " + gitHubToken
+		}
+		return "https://raw.githubusercontent.com/" + p.RemoteUsername + "/" + p.RemoteRepo + "/" +
+			sha + "/" + descriptionPath, token
+	}
+	if gitLabToken != "" {
+		token["Private-Token"] = gitLabToken
+	}
+	remoteHost := strings.TrimSuffix(GetGitRepositoryURL(p), "/"+p.RemoteUsername+"/"+p.RemoteRepo)
+	return remoteHost + "/api/v4/projects/" + url.PathEscape(p.RemoteUsername+"/"+p.RemoteRepo) +
+		"/repository/files/" + url.PathEscape(descriptionPath) + "/raw?ref=" + sha, token
+}
+
+// GetLatestVersionTag returns the name of the tag (e.g. 'refs/tags/v1.2.3') representing the highest
+// version among refNames which satisfies tagConstraints, or an empty string if there's no such tag.
+// Tags which don't look like version numbers (e.g. 'nightly' or 'v1.0.0-rc1') are ignored.
+func GetLatestVersionTag(refNames []string, tagConstraints []DependencyVersion) string {
+	var latestTag, latestVersion string
+	for _, refName := range refNames {
+		if !strings.HasPrefix(refName, tagRefPrefix) {
+			continue
+		}
+		match := versionTagRegexp.FindStringSubmatch(strings.TrimPrefix(refName, tagRefPrefix))
+		if match == nil || !CheckIfVersionConstraintsSatisfied(match[1], tagConstraints) {
+			continue
+		}
+		if latestTag == "" || CheckIfVersionSufficient(match[1], ">", latestVersion) {
+			latestTag = refName
+			latestVersion = match[1]
+		}
+	}
+	return latestTag
+}
+
+// GetGitUpdateRef returns the name of the git reference to which the package p should be updated,
+// according to the gitUpdatePolicy, based on the list of references refNames in the package repository.
+// An empty string means the default branch. With the 'track-ref' policy, the package can't be updated
+// if it has been locked to a branch which doesn't exist, or if the references couldn't be listed.
+// With the 'latest-tag' policy, only the tags satisfying tagConstraints are considered.
+// The second returned value is false if the package can't be updated according to the policy.
+func GetGitUpdateRef(p PackageDescription, gitUpdatePolicy string, refNames []string,
+	tagConstraints []DependencyVersion) (string, bool) {
+	switch gitUpdatePolicy {
+	case gitUpdatePolicyDefaultBranch:
+		return "", true
+	case gitUpdatePolicyTrackRef:
+		// Follow the branch if the package has been locked to a branch, and use the default branch
+		// only if the package has been locked to the default branch, an existing tag or a commit SHA.
+		switch {
+		case p.RemoteRef == "" || p.RemoteRef == "HEAD":
+			return "", true
+		case len(refNames) == 0:
+			log.Error("Could not list the git references in the repository of package ", p.Package,
+				". The package will not be updated.")
+			return "", false
+		case stringInSlice(branchRefPrefix+p.RemoteRef, refNames):
+			return branchRefPrefix + p.RemoteRef, true
+		case stringInSlice(tagRefPrefix+p.RemoteRef, refNames) || commitShaRegexp.MatchString(p.RemoteRef):
+			return "", true
+		}
+		log.Error("Reference ", p.RemoteRef, " of package ", p.Package, " not found in the repository. ",
+			"The package will not be updated.")
+		return "", false
+	case gitUpdatePolicyLatestTag:
+		latestTag := GetLatestVersionTag(refNames, tagConstraints)
+		if latestTag == "" {
+			log.Warn("No version tags satisfying the constraints found in the repository of package ", p.Package, ".")
+			return "", false
+		}
+		return latestTag, true
+	}
+	log.Fatal("Unknown --gitUpdatePolicy: ", gitUpdatePolicy, ". Please use one of: ",
+		gitUpdatePolicyTrackRef, ", ", gitUpdatePolicyDefaultBranch, ", ", gitUpdatePolicyLatestTag, ".")
+	return "", false
+}
+
+// GetGitCredentialsType returns the type of credentials (GitHub or GitLab token) which should be used
+// to access the git repository of package p, or an empty string if no credentials should be used.
+func GetGitCredentialsType(p PackageDescription) string {
+	if p.Source == GitHub && GetGitHubHost(p.RemoteHost) != gitHubHost {
+		// The GitHub token is not valid for GitHub Enterprise hosts.
+		return ""
+	}
+	return p.Source
+}
+
+// DownloadGitDescriptionFields downloads only the DESCRIPTION file of package p from the commit sha
+// with downloadFileFunction, and returns its fields, or nil in case of error.
+func DownloadGitDescriptionFields(p PackageDescription, sha string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) map[string]string {
+	descriptionURL, token := GetDescriptionFileURL(p, sha)
+	_, descriptionContent, err := downloadFileFunction(descriptionURL, token)
+	if err != nil {
+		log.Warn("An error occurred while downloading ", descriptionURL, ": ", err,
+			". The repository will be cloned instead.")
+		return nil
+	}
+	return ParseDCF(descriptionContent)
+}
+
+// CloneGitDescriptionFields clones the git repository of package p to gitDirectory with getGitRefShaFunction,
+// checking out the reference refName. It returns the fields of the package DESCRIPTION file from the clone,
+// together with the SHA and the short name of the checked out reference.
+func CloneGitDescriptionFields(p PackageDescription, refName string, gitDirectory string,
+	getGitRefShaFunction func(string, string, string, string) (string, string)) (map[string]string, string, string) {
+	sha, ref := getGitRefShaFunction(gitDirectory, GetGitRepositoryURL(p), GetGitCredentialsType(p), refName)
+	var remoteSubdir string
+	if p.RemoteSubdir != "" {
+		remoteSubdir = "/" + p.RemoteSubdir
+	}
+	return ReadDescriptionFields(gitDirectory + remoteSubdir + "/DESCRIPTION"), sha, ref
+}
+
+// CheckIfGitPackageUpdated checks whether the package p should be updated to newVersion at commit newSha
+// according to the gitUpdatePolicy. With the policies following a git reference ('track-ref' and
+// 'default-branch'), the package is updated whenever the SHA changes. With the 'latest-tag' policy,
+// the package is updated only to a higher version.
+func CheckIfGitPackageUpdated(p PackageDescription, newVersion string, newSha string, gitUpdatePolicy string) bool {
+	if newVersion == "" || newSha == "" || newSha == p.RemoteSha {
+		return false
+	}
+	if gitUpdatePolicy != gitUpdatePolicyLatestTag {
+		return true
+	}
+	if CheckIfVersionSufficient(newVersion, "<", p.Version) {
+		log.Warn("Not updating package ", p.Package, " as the latest tag version ", newVersion,
+			" is lower than the locked version ", p.Version, ".")
+	}
+	return CheckIfVersionSufficient(newVersion, ">", p.Version)
 }
 
 // UpdateGitPackages iterates through the packages in renv.lock and updates the entries
-// corresponding to packages stored in git repositories. Package version and latest commit SHA
-// are updated in the renvLock struct. Only packages matching the updatePackageRegexp are updated.
-func UpdateGitPackages(renvLock *RenvLock, updatePackageRegexp string,
-	getDefaultBranchShaFunction func(string, string, string) (string, string),
-	gitUpdatesDirectory string) {
+// corresponding to packages stored in git repositories. Package version and commit SHA
+// are updated in the renvLock struct according to the gitUpdatePolicy: 'track-ref' follows the branch
+// to which the package has been locked (or the default branch if the package has been locked to e.g. a tag
+// or a commit SHA),
+// 'default-branch' uses the default branch, and 'latest-tag' uses the tag with the highest version
+// satisfying tagConstraints (packages are never downgraded with this policy).
+// The references are listed with listGitRefsFunction, and only the DESCRIPTION file of the new commit
+// is downloaded with downloadFileFunction. If that's not possible, the repository is cloned to
+// gitUpdatesDirectory with getGitRefShaFunction instead.
+// Only packages matching the updatePackageRegexp are updated.
+// It returns a map from the names of updated packages to their new dependencies.
+func UpdateGitPackages(renvLock *RenvLock, updatePackageRegexp string, gitUpdatePolicy string,
+	tagConstraints []DependencyVersion,
+	listGitRefsFunction func(string, string) (map[string]string, string),
+	downloadFileFunction func(string, map[string]string) (int64, string, error),
+	getGitRefShaFunction func(string, string, string, string) (string, string),
+	gitUpdatesDirectory string) map[string][]Dependency {
+	updatedPackages := make(map[string][]Dependency)
 	for k, v := range renvLock.Packages {
 		match, err := regexp.MatchString(updatePackageRegexp, k)
 		checkError(err)
@@ -176,34 +485,57 @@ func UpdateGitPackages(renvLock *RenvLock, updatePackageRegexp string,
 		}
 		log.Trace("Package ", k, " matches updated packages regexp ",
 			updatePackageRegexp)
-		// Read default branch HEAD SHA from git.
-		newPackageSha, defaultBranchName := getDefaultBranchShaFunction(
-			gitUpdatesDirectory+k, GetGitRepositoryURL(v), v.Source,
-		)
-		var remoteSubdir string
-		if v.RemoteSubdir != "" {
-			remoteSubdir = "/" + v.RemoteSubdir
-		}
-		// Read newest package version from DESCRIPTION.
-		newPackageVersion := GetPackageVersionFromDescription(
-			gitUpdatesDirectory + k + remoteSubdir + "/DESCRIPTION")
-		if entry, ok := renvLock.Packages[k]; ok && newPackageSha != "" && newPackageVersion != "" {
-			if newPackageVersion != entry.Version && newPackageSha != entry.RemoteSha {
-				// Update the renv structure with new version only if the current
-				// default branch SHA and current package version could be retrieved
-				// and they are different than old ones.
-				log.Info("Updating package ", k, " version: ",
-					entry.Version, " → ", newPackageVersion,
-					", SHA: ", entry.RemoteSha, " → ", newPackageSha)
-				entry.Version = newPackageVersion
-				entry.RemoteSha = newPackageSha
-				// Set the default branch name to ensure consistency,
-				// in case previously renv.lock pointed to e.g. a tag.
-				entry.RemoteRef = defaultBranchName
-				renvLock.Packages[k] = entry
+		refShas, defaultBranch := listGitRefsFunction(GetGitRepositoryURL(v), GetGitCredentialsType(v))
+		var refNames []string
+		for refName := range refShas {
+			refNames = append(refNames, refName)
+		}
+		sort.Strings(refNames)
+		updateRef, ok := GetGitUpdateRef(v, gitUpdatePolicy, refNames, tagConstraints)
+		if !ok {
+			continue
+		}
+		var newPackageSha, newPackageRef string
+		var descriptionFields map[string]string
+		if updateRef == "" {
+			updateRef = defaultBranch
+		}
+		if sha, ok := refShas[updateRef]; ok && updateRef != "" {
+			newPackageSha = sha
+			newPackageRef = plumbing.ReferenceName(updateRef).Short()
+			if newPackageSha == v.RemoteSha {
+				log.Trace("Package ", k, " is up to date with ", newPackageRef, ".")
+				continue
 			}
+			descriptionFields = DownloadGitDescriptionFields(v, newPackageSha, downloadFileFunction)
+		}
+		if descriptionFields["Version"] == "" {
+			// Fall back to cloning the repository and reading the SHA and DESCRIPTION from the clone.
+			descriptionFields, newPackageSha, newPackageRef = CloneGitDescriptionFields(
+				v, updateRef, gitUpdatesDirectory+k, getGitRefShaFunction,
+			)
 		}
+		newPackageVersion := descriptionFields["Version"]
+		if !CheckIfGitPackageUpdated(v, newPackageVersion, newPackageSha, gitUpdatePolicy) {
+			continue
+		}
+		// Update the renv structure with new version only if the SHA of the reference
+		// and current package version could be retrieved.
+		log.Info("Updating package ", k, " version: ",
+			v.Version, " → ", newPackageVersion,
+			", SHA: ", v.RemoteSha, " → ", newPackageSha,
+			", ref: ", v.RemoteRef, " → ", newPackageRef,
+			" (", gitUpdatePolicy, " policy)")
+		v.Version = newPackageVersion
+		v.RemoteSha = newPackageSha
+		v.RemoteRef = newPackageRef
+		v.Fields = descriptionFields
+		updatedPackages[k] = GetDependenciesFromFields(descriptionFields)
+		v.Requirements = GetRequirements(updatedPackages[k])
+		v.Hash = GetPackageHash(v)
+		renvLock.Packages[k] = v
 	}
+	return updatedPackages
 }
 
 // GetLatestPackageVersionFromAnyRepository searches for the latest version of soughtPackageName
@@ -234,11 +566,51 @@ func GetLatestPackageVersionFromAnyRepository(soughtPackageName string, packages
 	return latestPackageVersionRepository
 }
 
+// GetAllowedPackageUpdate returns the package to which the package locked in lockedVersion can be updated,
+// given the latest version newPackage found in the PACKAGES file. If the latest version violates the
+// updateConstraints, the newest allowed version is retrieved from the Archive of the repository located at
+// repositoryURL using downloadFileFunction. If there's no such version, a package with the lockedVersion is returned.
+func GetAllowedPackageUpdate(lockedVersion string, newPackage PackageDescription, repositoryURL string,
+	updateConstraints []UpdateConstraint,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) PackageDescription {
+	allowedUpdate := GetAllowedUpdate(newPackage.Package, updateConstraints)
+	if newPackage.Version == lockedVersion || CheckIfUpdateAllowed(lockedVersion, newPackage.Version, allowedUpdate) {
+		return newPackage
+	}
+	archivedVersion, archivedFields := GetAllowedArchivedPackage(
+		repositoryURL, newPackage.Package, lockedVersion, allowedUpdate, downloadFileFunction,
+	)
+	if archivedVersion == "" {
+		log.Warn("Package ", newPackage.Package, " left unchanged in version ", lockedVersion,
+			" because the latest version ", newPackage.Version, " is not allowed by the '", allowedUpdate,
+			"' update constraint.")
+		return PackageDescription{
+			newPackage.Package, lockedVersion, "", "", []Dependency{},
+			"", "", "", "", "", "", "", []string{}, "", nil,
+		}
+	}
+	log.Warn("Package ", newPackage.Package, " will be updated to version ", archivedVersion, " from Archive because ",
+		"the latest version ", newPackage.Version, " is not allowed by the '", allowedUpdate, "' update constraint.")
+	return PackageDescription{
+		newPackage.Package, archivedVersion, "", "", GetDependenciesFromFields(archivedFields),
+		"", "", "", "", "", "", "", []string{}, "", archivedFields,
+	}
+}
+
 // UpdateRepositoryPackages iterates through the packages in renv.lock and updates the entries
 // corresponding to packages downloaded from CRAN-like repositories. Package version is updated
 // in the renvLock struct. Only packages matching the updatePackageRegexp are updated.
+// If the latest version of a package violates the updateConstraints, the newest allowed version
+// is retrieved from the repository Archive using downloadFileFunction, or the package is left unchanged.
+// It returns a map from the names of updated packages to their new dependencies.
 func UpdateRepositoryPackages(renvLock *RenvLock, updatePackageRegexp string,
-	packagesFiles map[string]PackagesFile) {
+	packagesFiles map[string]PackagesFile, updateConstraints []UpdateConstraint,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) map[string][]Dependency {
+	updatedPackages := make(map[string][]Dependency)
+	repositoryURLs := make(map[string]string)
+	for _, r := range renvLock.R.Repositories {
+		repositoryURLs[r.Name] = r.URL
+	}
 	for k, v := range renvLock.Packages {
 		match, err := regexp.MatchString(updatePackageRegexp, k)
 		checkError(err)
@@ -260,36 +632,226 @@ func UpdateRepositoryPackages(renvLock *RenvLock, updatePackageRegexp string,
 			repositoryName = GetLatestPackageVersionFromAnyRepository(k, packagesFiles)
 			repositoryPackagesFile = packagesFiles[repositoryName]
 		}
-		var newPackageVersion string
+		var newPackage PackageDescription
 		for _, singlePackage := range repositoryPackagesFile.Packages {
 			if singlePackage.Package == k {
-				newPackageVersion = singlePackage.Version
+				newPackage = singlePackage
 				break
 			}
 		}
-		if newPackageVersion == "" {
+		if newPackage.Version == "" {
 			log.Error(`Could not find package `, k, ` in PACKAGES file for "`, repositoryName, `" repository.`)
 			continue
 		}
-		if entry, ok := renvLock.Packages[k]; ok {
-			if newPackageVersion != entry.Version {
-				if notFoundRepositoryName != "" {
+		newPackage = GetAllowedPackageUpdate(
+			v.Version, newPackage, repositoryURLs[repositoryName], updateConstraints, downloadFileFunction,
+		)
+		if newPackage.Version == v.Version {
+			continue
+		}
+		if notFoundRepositoryName != "" {
+			log.Warn(
+				"Repository ", notFoundRepositoryName, " referenced by package ", k, " has not ",
+				"been defined in the lockfile and ", k, " will be updated to the latest version ",
+				`found in "`, repositoryName, `" repository.`,
+			)
+		}
+		log.Info("Updating package ", k, " version: ", v.Version, " → ", newPackage.Version)
+		v.Version = newPackage.Version
+		v.Fields = newPackage.Fields
+		v.Requirements = GetRequirements(newPackage.Dependencies)
+		v.Hash = GetPackageHash(v)
+		renvLock.Packages[k] = v
+		updatedPackages[k] = newPackage.Dependencies
+	}
+	return updatedPackages
+}
+
+// FindPackageInRepositories searches for the highest version of packageName satisfying the version
+// constraint in the PACKAGES files of repositories. If the same version is available in multiple repositories,
+// the first one in the order of repositoryNames is used. It returns the package and the name of the repository,
+// or an empty repository name if no such version could be found.
+func FindPackageInRepositories(packageName string, versionOperator string, versionValue string,
+	repositoryNames []string, packagesFiles map[string]PackagesFile) (PackageDescription, string) {
+	var foundPackage PackageDescription
+	var foundRepositoryName string
+	for _, repositoryName := range repositoryNames {
+		for _, p := range packagesFiles[repositoryName].Packages {
+			if p.Package == packageName && (versionOperator == "" ||
+				CheckIfVersionSufficient(p.Version, versionOperator, versionValue)) &&
+				(foundRepositoryName == "" || CheckIfVersionSufficient(p.Version, ">", foundPackage.Version)) {
+				foundPackage = p
+				foundRepositoryName = repositoryName
+			}
+		}
+	}
+	return foundPackage, foundRepositoryName
+}
+
+// CheckIfDependencyUpdateRequired checks whether the hard dependency d of packageName has to be added
+// to renvLock from a package repository, or updated to a version satisfying the version constraint.
+// Dependencies from git repositories are not updated, and an error is logged instead.
+func CheckIfDependencyUpdateRequired(renvLock RenvLock, packageName string, d Dependency) bool {
+	if (d.DependencyType != depends && d.DependencyType != imports && d.DependencyType != linkingTo) ||
+		CheckIfBasePackage(d.DependencyName) {
+		return false
+	}
+	entry, ok := renvLock.Packages[d.DependencyName]
+	if !ok {
+		return true
+	}
+	if d.VersionOperator == "" || CheckIfVersionSufficient(entry.Version, d.VersionOperator, d.VersionValue) {
+		return false
+	}
+	if entry.Source == GitHub || entry.Source == GitLab {
+		log.Error(
+			packageName, " requires ", d.DependencyName, " ", d.VersionOperator, " ", d.VersionValue,
+			" but version ", entry.Version, " from ", entry.Source, " is locked.",
+		)
+		return false
+	}
+	return true
+}
+
+// UpdateDependencies ensures that the hard dependencies (Depends, Imports, LinkingTo) of updatedPackages
+// (a map from the names of updated packages to their dependencies) are present in renvLock in the required
+// versions. Missing dependencies are added, and the versions of dependencies not satisfying the version
+// constraints are raised, based on the PACKAGES files of repositories defined in renvLock. The dependencies
+// of added and updated packages are processed in the same way. A warning is shown if the required version
+// change isn't allowed by updateConstraints.
+func UpdateDependencies(renvLock *RenvLock, updatedPackages map[string][]Dependency,
+	packagesFiles map[string]PackagesFile, updateConstraints []UpdateConstraint) {
+	var repositoryNames []string
+	for _, r := range renvLock.R.Repositories {
+		repositoryNames = append(repositoryNames, r.Name)
+	}
+	var queue []string
+	for k := range updatedPackages {
+		queue = append(queue, k)
+	}
+	// Process the packages in sorted order to generate predictable output.
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		packageName := queue[0]
+		queue = queue[1:]
+		for _, d := range updatedPackages[packageName] {
+			if !CheckIfDependencyUpdateRequired(*renvLock, packageName, d) {
+				continue
+			}
+			entry, ok := renvLock.Packages[d.DependencyName]
+			searchedRepositories := repositoryNames
+			if ok {
+				// Prefer the repository from which the package is currently downloaded.
+				searchedRepositories = append([]string{entry.Repository}, repositoryNames...)
+			}
+			p, repositoryName := FindPackageInRepositories(
+				d.DependencyName, d.VersionOperator, d.VersionValue, searchedRepositories, packagesFiles,
+			)
+			if repositoryName == "" {
+				log.Error(
+					"Could not find package ", d.DependencyName, " ", d.VersionOperator, " ", d.VersionValue,
+					" required by ", packageName, " in any of the repositories.",
+				)
+				continue
+			}
+			source := "Repository"
+			if ok {
+				source = entry.Source
+				log.Info("Updating package ", d.DependencyName, " version: ", entry.Version, " → ", p.Version,
+					" as required by ", packageName)
+				allowedUpdate := GetAllowedUpdate(d.DependencyName, updateConstraints)
+				if !CheckIfUpdateAllowed(entry.Version, p.Version, allowedUpdate) {
 					log.Warn(
-						"Repository ", notFoundRepositoryName, " referenced by package ", k, " has not ",
-						"been defined in the lockfile and ", k, " will be updated to the latest version ",
-						`found in "`, repositoryName, `" repository.`,
+						"Update of ", d.DependencyName, " from version ", entry.Version, " to ", p.Version,
+						" required by ", packageName, " violates the update constraint (", allowedUpdate, ").",
 					)
 				}
-				log.Info("Updating package ", k, " version: ",
-					entry.Version, " → ", newPackageVersion)
-				entry.Version = newPackageVersion
-				entry.Hash = ""
-				renvLock.Packages[k] = entry
+			} else {
+				log.Info("Adding package ", d.DependencyName, " version ", p.Version, " from ", repositoryName,
+					" repository as required by ", packageName)
+			}
+			newEntry := PackageDescription{
+				p.Package, p.Version, source, repositoryName, []Dependency{},
+				"", "", "", "", "", "", "", GetRequirements(p.Dependencies), "", p.Fields,
 			}
+			newEntry.Hash = GetPackageHash(newEntry)
+			renvLock.Packages[d.DependencyName] = newEntry
+			updatedPackages[d.DependencyName] = p.Dependencies
+			queue = append(queue, d.DependencyName)
 		}
 	}
 }
 
+// GetUpdatedPackageDependencies returns a map from the names of packages in renvLock to the names of their
+// hard dependencies. For updatedPackages (including the packages added as their dependencies), the Requirements
+// determined during the update are used. For the other packages, the dependencies are read from the records
+// of the input lockfile as described for GetLockedPackageDependencies, so that records without Requirements
+// (e.g. written by older versions of renv or locksmith) are taken into account.
+func GetUpdatedPackageDependencies(renvLock RenvLock, updatedPackages map[string][]Dependency,
+	records map[string]LockfileRecord,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) map[string][]string {
+	notUpdatedRenvLock := RenvLock{renvLock.R, renvLock.Bioconductor, make(map[string]PackageDescription)}
+	for k, p := range renvLock.Packages {
+		if _, ok := updatedPackages[k]; !ok {
+			notUpdatedRenvLock.Packages[k] = p
+		}
+	}
+	dependencies := GetLockedPackageDependencies(notUpdatedRenvLock, records, downloadFileFunction)
+	for k := range updatedPackages {
+		if p, ok := renvLock.Packages[k]; ok {
+			dependencies[k] = p.Requirements
+		}
+	}
+	return dependencies
+}
+
+// PruneDependencies removes from renvLock the packages which, according to previousRequirements
+// (a map from the names of updated packages to their requirements before the update), were required
+// by the updated packages, but are no longer required by any package in renvLock according to the
+// dependencies map. The dependencies of removed packages are processed in the same way.
+// If the dependencies of any package in renvLock are unknown, no packages are removed,
+// as they might be required by that package.
+func PruneDependencies(renvLock *RenvLock, previousRequirements map[string][]string,
+	dependencies map[string][]string) {
+	var unknownDependencies []string
+	for k := range renvLock.Packages {
+		if _, ok := dependencies[k]; !ok {
+			unknownDependencies = append(unknownDependencies, k)
+		}
+	}
+	if len(unknownDependencies) > 0 {
+		sort.Strings(unknownDependencies)
+		log.Warn("Could not determine the dependencies of: ", strings.Join(unknownDependencies, ", "),
+			". No packages have been pruned, as they might be required by these packages.")
+		return
+	}
+	var queue []string
+	for _, requirements := range previousRequirements {
+		queue = append(queue, requirements...)
+	}
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		packageName := queue[0]
+		queue = queue[1:]
+		if _, ok := renvLock.Packages[packageName]; !ok {
+			continue
+		}
+		var required bool
+		for k := range renvLock.Packages {
+			if stringInSlice(packageName, dependencies[k]) {
+				required = true
+				break
+			}
+		}
+		if required {
+			continue
+		}
+		log.Info("Removing package ", packageName, " as it's no longer required by any package.")
+		delete(renvLock.Packages, packageName)
+		queue = append(queue, dependencies[packageName]...)
+	}
+}
+
 // GetPackagesFiles downloads PACKAGES files from repositories defined in the renv.lock header.
 // It returns a map from the repository name (as defined in the renv.lock header) to the PackagesFile
 // struct representing repository's PACKAGES file.
@@ -303,30 +865,75 @@ func GetPackagesFiles(renvLock RenvLock) map[string]PackagesFile {
 	return repositoryPackagesFiles
 }
 
+// ReadRenvLock reads the renv.lock from inputFileName into RenvLock struct.
+func ReadRenvLock(inputFileName string) RenvLock {
+	var renvLock RenvLock
+	byteValue, err := os.ReadFile(inputFileName)
+	if err != nil {
+		log.Fatal("Could not read lockfile ", inputFileName, ": ", err)
+	}
+	err = json.Unmarshal(byteValue, &renvLock)
+	if err != nil {
+		log.Fatal("Could not parse lockfile ", inputFileName, ": ", err)
+	}
+	return renvLock
+}
+
 // UpdateRenvLock reads the renv.lock from inputFileName. It then retrieves the information
 // about the newest package versions from respective repositories (CRAN-like or git repositories)
 // from which the packages should be downloaded according to the renv.lock.
+// Only package versions which can be installed on targetPlatform with R version rVersion are taken
+// into account. The dependencies of updated packages are added or updated as required, and if pruneDependencies
+// is true, the packages no longer required by any package are removed. Packages from git repositories
+// are updated according to the gitUpdatePolicy, and with the 'latest-tag' policy only the tags satisfying
+// the comma-separated gitTagConstraint (e.g. '>= 1.2.0, < 2.0.0') are considered. Packages from package
+// repositories are updated only as allowed by updateConstraints. If snapshotDate is set, the packages are
+// updated from the snapshots of CRAN-like repositories as of that date.
 // It returns the RenvLock struct represeting the renv.lock with updated package versions.
-func UpdateRenvLock(inputFileName, updatePackages string) RenvLock {
-	// Read renv.lock into RenvLock struct.
-	var renvLock RenvLock
-	byteValue, err := os.ReadFile(inputFileName)
-	checkError(err)
-	err = json.Unmarshal(byteValue, &renvLock)
-	checkError(err)
+func UpdateRenvLock(inputFileName, updatePackages, targetPlatform, rVersion string, pruneDependencies bool,
+	gitUpdatePolicy, gitTagConstraint string, updateConstraints []UpdateConstraint,
+	snapshotDate string) RenvLock {
+	renvLock := ReadRenvLock(inputFileName)
+	ApplySnapshotDate(&renvLock, snapshotDate)
+	previousRequirements := make(map[string][]string)
+	for k, p := range renvLock.Packages {
+		previousRequirements[k] = p.Requirements
+	}
 
 	updatePackageRegex := GetPackageRegex(updatePackages)
 
 	// Remove and recreate directories where temporary clones of git repositories
-	// used to get the newest default branch SHA will be stored.
+	// used to get the newest SHA of git packages will be stored.
 	gitUpdatesDirectory := localTempDirectory + "/git_updates/"
-	err = os.RemoveAll(gitUpdatesDirectory)
+	err := os.RemoveAll(gitUpdatesDirectory)
 	checkError(err)
 	err = os.MkdirAll(gitUpdatesDirectory, os.ModePerm)
 	checkError(err)
 
-	UpdateGitPackages(&renvLock, updatePackageRegex, GetDefaultBranchSha, gitUpdatesDirectory)
+	updatedPackages := UpdateGitPackages(
+		&renvLock, updatePackageRegex, gitUpdatePolicy, ParseVersionConstraints(gitTagConstraint),
+		ListGitRefs, DownloadTextFile, GetGitRefSha, gitUpdatesDirectory,
+	)
 	repositoryPackagesFiles := GetPackagesFiles(renvLock)
-	UpdateRepositoryPackages(&renvLock, updatePackageRegex, repositoryPackagesFiles)
+	FilterPackagesFiles(repositoryPackagesFiles, targetPlatform, rVersion)
+	for k, v := range UpdateRepositoryPackages(
+		&renvLock, updatePackageRegex, repositoryPackagesFiles, updateConstraints, DownloadTextFile,
+	) {
+		updatedPackages[k] = v
+	}
+	UpdateDependencies(&renvLock, updatedPackages, repositoryPackagesFiles, updateConstraints)
+	if pruneDependencies {
+		updatedPackagesPreviousRequirements := make(map[string][]string)
+		for k := range updatedPackages {
+			updatedPackagesPreviousRequirements[k] = previousRequirements[k]
+		}
+		content, err := os.ReadFile(inputFileName)
+		checkError(err)
+		dependencies := GetUpdatedPackageDependencies(
+			renvLock, updatedPackages, GetLockfileRecords(content), DownloadTextFile,
+		)
+		PruneDependencies(&renvLock, updatedPackagesPreviousRequirements, dependencies)
+	}
+	RemoveUnresolvedRequirements(&renvLock)
 	return renvLock
 }
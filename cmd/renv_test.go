@@ -17,6 +17,10 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,7 +41,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 			"subdirectory1",
 			"main",
 			"aaabbb444333",
-			[]string{}, "",
+			[]string{}, "", nil,
 		},
 		{
 			"package2",
@@ -52,10 +56,10 @@ func Test_GenerateRenvLock(t *testing.T) {
 			"subdirectory2",
 			"v2.5.4.3",
 			"eee888222aaa",
-			[]string{}, "",
+			[]string{}, "", nil,
 		},
 		{
-			"", "", "", "", []Dependency{}, "", "", "", "", "", "", "", []string{}, "",
+			"", "", "", "", []Dependency{}, "", "", "", "", "", "", "", []string{}, "", nil,
 		},
 		{
 			"package3",
@@ -63,7 +67,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 			"Repository",
 			"https://repo1.example.com/repo1",
 			[]Dependency{},
-			"", "", "", "", "", "", "", []string{}, "",
+			"", "", "", "", "", "", "", []string{}, "", nil,
 		},
 		{
 			"package4",
@@ -71,7 +75,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 			"Repository",
 			"https://repo2.example.com/repo2",
 			[]Dependency{},
-			"", "", "", "", "", "", "", []string{}, "",
+			"", "", "", "", "", "", "", []string{}, "", nil,
 		},
 		{
 			"package5",
@@ -79,21 +83,23 @@ func Test_GenerateRenvLock(t *testing.T) {
 			"Repository",
 			"https://repo3.example.com/repo3",
 			[]Dependency{},
-			"", "", "", "", "", "", "", []string{}, "",
+			"", "", "", "", "", "", "", []string{}, "", nil,
 		},
 	}, map[string]string{
 		"Repo1": "https://repo1.example.com/repo1",
 		"Repo2": "https://repo2.example.com/repo2",
 		"Repo3": "https://repo3.example.com/repo3",
-	})
+	}, "4.4.1", "3.18")
 	assert.Equal(t, renvLock, RenvLock{
 		RenvLockContents{
+			"4.4.1",
 			[]RenvLockRepository{
 				{"Repo1", "https://repo1.example.com/repo1"},
 				{"Repo2", "https://repo2.example.com/repo2"},
 				{"Repo3", "https://repo3.example.com/repo3"},
 			},
 		},
+		&RenvLockBioconductor{"3.18"},
 		map[string]PackageDescription{
 			"package1": {
 				"package1",
@@ -108,7 +114,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 				"subdirectory1",
 				"main",
 				"aaabbb444333",
-				[]string{}, "",
+				[]string{}, "cf283189367beffedb6cbd981fd911e4", nil,
 			},
 			"package2": {
 				"package2",
@@ -123,7 +129,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 				"subdirectory2",
 				"v2.5.4.3",
 				"eee888222aaa",
-				[]string{}, "",
+				[]string{}, "91b728ec9609ead88cff014fcef7db08", nil,
 			},
 			"package3": {
 				"package3",
@@ -131,7 +137,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 				"Repository",
 				"Repo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package4": {
 				"package4",
@@ -139,7 +145,7 @@ func Test_GenerateRenvLock(t *testing.T) {
 				"Repository",
 				"Repo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package5": {
 				"package5",
@@ -147,12 +153,55 @@ func Test_GenerateRenvLock(t *testing.T) {
 				"Repository",
 				"Repo3",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
 	})
 }
 
+func Test_GetBioconductorVersion(t *testing.T) {
+	repositoryList := []string{
+		"https://cloud.r-project.org",
+		"https://bioconductor.org/packages/3.18/bioc",
+		"https://bioconductor.org/packages/3.18/data/annotation",
+	}
+	assert.Equal(t, GetBioconductorVersion("", repositoryList), "3.18")
+	assert.Equal(t, GetBioconductorVersion("3.19", repositoryList), "3.19")
+	assert.Equal(t, GetBioconductorVersion("", []string{"https://bioconductor.org/packages/release/bioc"}), "")
+}
+
+func Test_RenvLockHeaderRoundTrip(t *testing.T) {
+	input := `{"R":{"Version":"4.3.2","Repositories":[{"Name":"CRAN","URL":"https://cloud.r-project.org"}]},` +
+		`"Bioconductor":{"Version":"3.18"},"Packages":{}}`
+	var renvLock RenvLock
+	err := json.Unmarshal([]byte(input), &renvLock)
+	assert.Nil(t, err)
+	assert.Equal(t, renvLock.R.Version, "4.3.2")
+	assert.Equal(t, renvLock.Bioconductor, &RenvLockBioconductor{"3.18"})
+	output, err := json.Marshal(renvLock)
+	assert.Nil(t, err)
+	assert.Equal(t, string(output), input)
+}
+
+func Test_RemoveUnresolvedRequirements(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"Matrix", "R", "excludedPackage", "package2", "utils"}, "", nil,
+			},
+			"package2": {
+				"package2", "2.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	}
+	RemoveUnresolvedRequirements(&renvLock)
+	assert.Equal(t, renvLock.Packages["package1"].Requirements, []string{"Matrix", "R", "package2", "utils"})
+	assert.Equal(t, renvLock.Packages["package2"].Requirements, []string{})
+}
+
 func Test_GetPackageRegex(t *testing.T) {
 	packageRegex := GetPackageRegex("package*,*some.Package,test1,my*awesome*package")
 	assert.Equal(t, packageRegex, `^package.*$|^.*some\.Package$|^test1$|^my.*awesome.*package$`)
@@ -170,38 +219,117 @@ func Test_GetPackageVersionFromDescription(t *testing.T) {
 func Test_GetGitRepositoryURL(t *testing.T) {
 	repoURL1 := GetGitRepositoryURL(PackageDescription{
 		"", "", "GitHub", "", []Dependency{}, "",
-		"api.github.com", "github-org-1", "repo-name-1", "", "", "", []string{}, "",
+		"api.github.com", "github-org-1", "repo-name-1", "", "", "", []string{}, "", nil,
 	})
 	assert.Equal(t, repoURL1, "https://github.com/github-org-1/repo-name-1")
 	repoURL2 := GetGitRepositoryURL(PackageDescription{
 		"", "", "GitLab", "", []Dependency{}, "",
-		"https://gitlab.example.com", "org1/org2", "repo-name-2", "", "", "", []string{}, "",
+		"https://gitlab.example.com", "org1/org2", "repo-name-2", "", "", "", []string{}, "", nil,
 	})
 	assert.Equal(t, repoURL2, "https://gitlab.example.com/org1/org2/repo-name-2")
 	repoURL3 := GetGitRepositoryURL(PackageDescription{
 		"", "", "GitLab", "", []Dependency{}, "",
-		"gitlab.example.com", "org3/org4", "repo-name-3", "", "", "", []string{}, "",
+		"gitlab.example.com", "org3/org4", "repo-name-3", "", "", "", []string{}, "", nil,
 	})
 	assert.Equal(t, repoURL3, "https://gitlab.example.com/org3/org4/repo-name-3")
+	repoURL4 := GetGitRepositoryURL(PackageDescription{
+		"", "", "GitHub", "", []Dependency{}, "",
+		"github.example.com/api/v3", "github-org-4", "repo-name-4", "", "", "", []string{}, "", nil,
+	})
+	assert.Equal(t, repoURL4, "https://github.example.com/github-org-4/repo-name-4")
+}
+
+func Test_GetGitHubHost(t *testing.T) {
+	assert.Equal(t, GetGitHubHost("api.github.com"), "github.com")
+	assert.Equal(t, GetGitHubHost(""), "github.com")
+	assert.Equal(t, GetGitHubHost("github.example.com/api/v3"), "github.example.com")
+	assert.Equal(t, GetGitHubHost("https://github.example.com/api/v3/"), "github.example.com")
+}
+
+func Test_GetGitHubAPIURL(t *testing.T) {
+	assert.Equal(t, GetGitHubAPIURL("api.github.com"), "https://api.github.com")
+	assert.Equal(t, GetGitHubAPIURL("github.example.com/api/v3"), "https://github.example.com/api/v3")
 }
 
-func mockedGetDefaultBranchSha(_ string, repoURL string, _ string) (string, string) {
-	switch {
-	case repoURL == "https://github.com/group1/group2/package11":
-		return "eee111555bbbccc", "main"
-	case repoURL == "https://gitlab.example.com/group3/group4/package12":
+func mockedGetGitRefSha(_ string, repoURL string, _ string, refName string) (string, string) {
+	if repoURL == "https://gitlab.example.com/group3/group4/package12" && refName == "refs/heads/main" {
 		return "888444dddbbbaaa", "main"
 	}
 	return "", ""
 }
 
+func mockedListGitRefs(repoURL string, _ string) (map[string]string, string) {
+	switch repoURL {
+	case "https://github.com/group1/group2/package11":
+		return map[string]string{
+			"refs/heads/main":        "ddd000111222",
+			"refs/heads/release-1.x": "eee111555bbbccc0123456789abcdef012345678",
+			"refs/tags/v1.0.2":       "aaabbb444333",
+		}, "refs/heads/main"
+	case "https://gitlab.example.com/group3/group4/package12":
+		return map[string]string{
+			"refs/heads/main":    "888444dddbbbaaa",
+			"refs/tags/v2.5.4.3": "eee888222aaa",
+			"refs/tags/v2.6.1.1": "999555aaaccc",
+			"refs/tags/v3.0.0":   "fff000999888",
+		}, "refs/heads/main"
+	}
+	return map[string]string{}, ""
+}
+
+func mockedDownloadGitDescriptionFile(url string, _ map[string]string) (int64, string, error) {
+	var descriptionFilePath string
+	switch url {
+	case "https://raw.githubusercontent.com/group1/group2/package11/" +
+		"eee111555bbbccc0123456789abcdef012345678/subdirectory1/DESCRIPTION":
+		descriptionFilePath = "testdata/git_updates/package11/subdirectory1/DESCRIPTION"
+	case "https://gitlab.example.com/api/v4/projects/group3%2Fgroup4%2Fpackage12/repository/files/" +
+		"subdirectory2%2FDESCRIPTION/raw?ref=999555aaaccc":
+		descriptionFilePath = "testdata/git_updates/package12/subdirectory2/DESCRIPTION"
+	default:
+		return 0, "", errors.New("Received status code 404")
+	}
+	content, err := os.ReadFile(descriptionFilePath)
+	return int64(len(content)), string(content), err
+}
+
+func Test_GetDescriptionFileURL(t *testing.T) {
+	defaultGitHubToken := gitHubToken
+	defer func() { gitHubToken = defaultGitHubToken }()
+	gitHubToken = "github-token"
+	descriptionURL, token := GetDescriptionFileURL(PackageDescription{
+		"package1", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "package1", "", "main", "aaabbb444333",
+		[]string{}, "", nil,
+	}, "ccc111")
+	assert.Equal(t, descriptionURL, "https://raw.githubusercontent.com/org1/package1/ccc111/DESCRIPTION")
+	assert.Equal(t, token, map[string]string{"Authorization": "token github-token"})
+	descriptionURL, token = GetDescriptionFileURL(PackageDescription{
+		"package3", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "github.example.com/api/v3", "org3", "package3", "sub", "main", "aaabbb444333",
+		[]string{}, "", nil,
+	}, "ccc333")
+	assert.Equal(t, descriptionURL,
+		"https://github.example.com/api/v3/repos/org3/package3/contents/sub/DESCRIPTION?ref=ccc333")
+	assert.Equal(t, token, map[string]string{"Accept": "application/vnd.github.raw"})
+	descriptionURL, _ = GetDescriptionFileURL(PackageDescription{
+		"package2", "1.0.0", "GitLab", "", []Dependency{},
+		"gitlab", "gitlab.example.com", "group1/group2", "package2", "sub/dir", "main", "aaabbb444333",
+		[]string{}, "", nil,
+	}, "ccc222")
+	assert.Equal(t, descriptionURL, "https://gitlab.example.com/api/v4/projects/group1%2Fgroup2%2Fpackage2/"+
+		"repository/files/sub%2Fdir%2FDESCRIPTION/raw?ref=ccc222")
+}
+
 func Test_UpdateGitPackages(t *testing.T) {
 	renvLock := RenvLock{
 		RenvLockContents{
+			"",
 			[]RenvLockRepository{
 				{"Repo1", "https://repo1.example.com/repo1"},
 			},
 		},
+		nil,
 		map[string]PackageDescription{
 			"package11": {
 				"package11",
@@ -214,9 +342,9 @@ func Test_UpdateGitPackages(t *testing.T) {
 				"group1/group2",
 				"package11",
 				"subdirectory1",
-				"main",
+				"release-1.x",
 				"aaabbb444333",
-				[]string{}, "",
+				[]string{}, "", nil,
 			},
 			"package12": {
 				"package12",
@@ -231,7 +359,7 @@ func Test_UpdateGitPackages(t *testing.T) {
 				"subdirectory2",
 				"v2.5.4.3",
 				"eee888222aaa",
-				[]string{}, "",
+				[]string{}, "", nil,
 			},
 			"package3": {
 				"package3",
@@ -239,7 +367,7 @@ func Test_UpdateGitPackages(t *testing.T) {
 				"Repository",
 				"Repo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package4": {
 				"package4",
@@ -254,31 +382,189 @@ func Test_UpdateGitPackages(t *testing.T) {
 				"",
 				"v3.7.0",
 				"ccceee444999",
-				[]string{}, "",
+				[]string{}, "", nil,
 			},
 		},
 	}
-	UpdateGitPackages(&renvLock, "^package1.*$", mockedGetDefaultBranchSha, "testdata/git_updates/")
+	UpdateGitPackages(
+		&renvLock, "^package1.*$", gitUpdatePolicyTrackRef, []DependencyVersion{}, mockedListGitRefs,
+		mockedDownloadGitDescriptionFile, mockedGetGitRefSha, "testdata/git_updates/",
+	)
 	assert.Equal(t, renvLock.Packages["package11"].Version, "1.0.4")
 	assert.Equal(t, renvLock.Packages["package12"].Version, "2.6.1.1")
 	assert.Equal(t, renvLock.Packages["package4"].Version, "3.7.0")
-	assert.Equal(t, renvLock.Packages["package11"].RemoteSha, "eee111555bbbccc")
+	assert.Equal(t, renvLock.Packages["package11"].RemoteSha, "eee111555bbbccc0123456789abcdef012345678")
 	assert.Equal(t, renvLock.Packages["package12"].RemoteSha, "888444dddbbbaaa")
 	assert.Equal(t, renvLock.Packages["package4"].RemoteSha, "ccceee444999")
-	assert.Equal(t, renvLock.Packages["package11"].RemoteRef, "main")
+	assert.Equal(t, renvLock.Packages["package11"].RemoteRef, "release-1.x")
 	assert.Equal(t, renvLock.Packages["package12"].RemoteRef, "main")
+	// The update policy is not saved in the package record.
+	_, ok := renvLock.Packages["package11"].Fields["UpdatePolicy"]
+	assert.False(t, ok)
 	assert.Equal(t, renvLock.Packages["package4"].RemoteRef, "v3.7.0")
+	// MD5 sum of: "Package: package11\nVersion: 1.0.4\nRemoteHost: api.github.com\nRemoteRef: release-1.x\n" +
+	// "RemoteRepo: package11\nRemoteSha: eee111555bbbccc0123456789abcdef012345678\n" +
+	// "RemoteSubdir: subdirectory1\nRemoteType: github\nRemoteUsername: group1/group2\n"
+	assert.Equal(t, renvLock.Packages["package11"].Hash, "7b7133b0fbe030a4130232a9ecb4cbb6")
+	assert.Equal(t, renvLock.Packages["package4"].Hash, "")
+}
+
+func Test_UpdateGitPackagesLatestTag(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package12": {
+				"package12", "2.5.4.3", "GitLab", "", []Dependency{},
+				"gitlab", "https://gitlab.example.com", "group3/group4", "package12", "subdirectory2",
+				"v2.5.4.3", "eee888222aaa", []string{}, "", nil,
+			},
+		},
+	}
+	updatedPackages := UpdateGitPackages(
+		&renvLock, "^package1.*$", gitUpdatePolicyLatestTag, ParseVersionConstraints("< 3"),
+		mockedListGitRefs, mockedDownloadGitDescriptionFile, mockedGetGitRefSha, "testdata/git_updates/",
+	)
+	assert.Contains(t, updatedPackages, "package12")
+	assert.Equal(t, renvLock.Packages["package12"].Version, "2.6.1.1")
+	assert.Equal(t, renvLock.Packages["package12"].RemoteRef, "v2.6.1.1")
+	assert.Equal(t, renvLock.Packages["package12"].RemoteSha, "999555aaaccc")
+}
+
+func Test_UpdateGitPackagesSameVersion(t *testing.T) {
+	// New commits on the tracked branch which don't change the package version.
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package11": {
+				"package11", "1.0.4", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "group1/group2", "package11", "subdirectory1",
+				"release-1.x", "aaabbb444333", []string{}, "", nil,
+			},
+		},
+	}
+	updatedPackages := UpdateGitPackages(
+		&renvLock, "^package11$", gitUpdatePolicyTrackRef, []DependencyVersion{}, mockedListGitRefs,
+		mockedDownloadGitDescriptionFile, mockedGetGitRefSha, "testdata/git_updates/",
+	)
+	assert.Contains(t, updatedPackages, "package11")
+	assert.Equal(t, renvLock.Packages["package11"].Version, "1.0.4")
+	assert.Equal(t, renvLock.Packages["package11"].RemoteSha, "eee111555bbbccc0123456789abcdef012345678")
+	assert.Equal(t, renvLock.Packages["package11"].RemoteRef, "release-1.x")
+}
+
+func Test_CheckIfGitPackageUpdated(t *testing.T) {
+	p := PackageDescription{
+		"package1", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "package1", "", "main", "aaa111", []string{}, "", nil,
+	}
+	assert.True(t, CheckIfGitPackageUpdated(p, "1.0.0", "bbb222", gitUpdatePolicyTrackRef))
+	assert.True(t, CheckIfGitPackageUpdated(p, "1.0.0", "bbb222", gitUpdatePolicyDefaultBranch))
+	assert.False(t, CheckIfGitPackageUpdated(p, "1.0.1", "aaa111", gitUpdatePolicyTrackRef))
+	assert.False(t, CheckIfGitPackageUpdated(p, "", "bbb222", gitUpdatePolicyTrackRef))
+	assert.False(t, CheckIfGitPackageUpdated(p, "1.0.0", "bbb222", gitUpdatePolicyLatestTag))
+	assert.False(t, CheckIfGitPackageUpdated(p, "0.9.0", "bbb222", gitUpdatePolicyLatestTag))
+	assert.True(t, CheckIfGitPackageUpdated(p, "1.1.0", "bbb222", gitUpdatePolicyLatestTag))
+}
+
+func Test_GetGitCredentialsType(t *testing.T) {
+	p := PackageDescription{
+		"package1", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "package1", "", "main", "aaa111", []string{}, "", nil,
+	}
+	assert.Equal(t, GetGitCredentialsType(p), GitHub)
+	p.RemoteHost = "github.example.com/api/v3"
+	assert.Equal(t, GetGitCredentialsType(p), "")
+	p.Source = GitLab
+	p.RemoteHost = "https://gitlab.example.com"
+	assert.Equal(t, GetGitCredentialsType(p), GitLab)
+}
+
+func Test_GetLatestVersionTag(t *testing.T) {
+	refNames := []string{
+		"HEAD", "refs/heads/v9.0.0", "refs/tags/v1.10.0", "refs/tags/v1.9.2", "refs/tags/nightly", "refs/tags/1.2-3",
+		"refs/tags/v2.0.0-rc1",
+	}
+	assert.Equal(t, GetLatestVersionTag(refNames, []DependencyVersion{}), "refs/tags/v1.10.0")
+	assert.Equal(t, GetLatestVersionTag(refNames, ParseVersionConstraints(">= 1.2, < 1.10")), "refs/tags/v1.9.2")
+	assert.Equal(t, GetLatestVersionTag(refNames, ParseVersionConstraints("> 2.0")), "")
+	assert.Equal(t, GetLatestVersionTag([]string{"refs/heads/main", "refs/tags/nightly"}, nil), "")
+}
+
+func Test_GetGitUpdateRef(t *testing.T) {
+	p := PackageDescription{
+		"package1", "1.0.0", "GitHub", "", []Dependency{},
+		"github", "api.github.com", "org1", "package1", "", "release-1.x", "aaabbb444333",
+		[]string{}, "", nil,
+	}
+	refNames := []string{"HEAD", "refs/heads/main", "refs/heads/release-1.x", "refs/tags/v1.0.0", "refs/tags/v2.0.0"}
+	updateRef, ok := GetGitUpdateRef(p, gitUpdatePolicyTrackRef, refNames, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "refs/heads/release-1.x")
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyDefaultBranch, refNames, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "")
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyLatestTag, refNames, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "refs/tags/v2.0.0")
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyLatestTag, refNames, ParseVersionConstraints("< 2"))
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "refs/tags/v1.0.0")
+	p.RemoteRef = "v1.0.0"
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyTrackRef, refNames, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "")
+	_, ok = GetGitUpdateRef(p, gitUpdatePolicyLatestTag, []string{"refs/heads/main"}, nil)
+	assert.False(t, ok)
+	p.RemoteRef = "aaabbb4"
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyTrackRef, refNames, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "")
+	p.RemoteRef = "HEAD"
+	updateRef, ok = GetGitUpdateRef(p, gitUpdatePolicyTrackRef, []string{}, nil)
+	assert.True(t, ok)
+	assert.Equal(t, updateRef, "")
+	// Deleted branch.
+	p.RemoteRef = "release-0.x"
+	_, ok = GetGitUpdateRef(p, gitUpdatePolicyTrackRef, refNames, nil)
+	assert.False(t, ok)
+	// Listing the references failed.
+	p.RemoteRef = "release-1.x"
+	_, ok = GetGitUpdateRef(p, gitUpdatePolicyTrackRef, []string{}, nil)
+	assert.False(t, ok)
+}
+
+func Test_UpdateGitPackagesListingFailed(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.2", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package1", "", "release-1.x", "aaabbb444333",
+				[]string{}, "", nil,
+			},
+		},
+	}
+	updatedPackages := UpdateGitPackages(
+		&renvLock, "^package1$", gitUpdatePolicyTrackRef, []DependencyVersion{}, mockedListGitRefs,
+		mockedDownloadGitDescriptionFile, mockedGetGitRefSha, "testdata/git_updates/",
+	)
+	assert.Empty(t, updatedPackages)
+	assert.Equal(t, renvLock.Packages["package1"].Version, "1.0.2")
+	assert.Equal(t, renvLock.Packages["package1"].RemoteRef, "release-1.x")
+	assert.Equal(t, renvLock.Packages["package1"].RemoteSha, "aaabbb444333")
 }
 
 func Test_UpdateRepositoryPackages(t *testing.T) {
 	renvLock := RenvLock{
 		RenvLockContents{
+			"",
 			[]RenvLockRepository{
 				{"Repo1", "https://repo1.example.com/ExampleRepo1"},
 				{"Repo2", "https://repo2.example.com/ExampleRepo2"},
 				{"Repo3", "https://repo3.example.com/ExampleRepo3"},
 			},
 		},
+		nil,
 		map[string]PackageDescription{
 			"package13": {
 				"package13",
@@ -286,7 +572,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package14": {
 				"package14",
@@ -301,7 +587,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"",
 				"v3.7.0",
 				"ccceee444999",
-				[]string{}, "",
+				[]string{}, "", nil,
 			},
 			"package15": {
 				"package15",
@@ -309,7 +595,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package16": {
 				"package16",
@@ -317,7 +603,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo3",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package17": {
 				"package17",
@@ -325,7 +611,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package18": {
 				"package18",
@@ -333,7 +619,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo2",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package19": {
 				"package19",
@@ -341,7 +627,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"NonExistentRepository",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			"package21": {
 				"package21",
@@ -349,7 +635,7 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"Repository",
 				"Repo1",
 				[]Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
 	}
@@ -360,21 +646,22 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"package13",
 				"2.2.0",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package21",
 				"3.9.3",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package19",
 				"5.2.1",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
 	packagesFiles["Repo2"] = PackagesFile{
 		[]PackageDescription{
@@ -382,15 +669,16 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"package15",
 				"3.2.1",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package19",
 				"5.2.2",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
 	packagesFiles["Repo3"] = PackagesFile{
 		[]PackageDescription{
@@ -398,17 +686,18 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 				"package16",
 				"1.2.3",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 			{
 				"package19",
 				"5.2.2.4",
 				"", "", []Dependency{},
-				"", "", "", "", "", "", "", []string{}, "",
+				"", "", "", "", "", "", "", []string{}, "", nil,
 			},
 		},
+		nil,
 	}
-	UpdateRepositoryPackages(&renvLock, "^package1.*$", packagesFiles)
+	UpdateRepositoryPackages(&renvLock, "^package1.*$", packagesFiles, nil, mockedDownloadTextFile)
 	assert.Equal(t, renvLock.Packages["package13"].Version, "2.2.0")
 	assert.Equal(t, renvLock.Packages["package14"].Version, "3.7.0")
 	assert.Equal(t, renvLock.Packages["package15"].Version, "3.2.1")
@@ -417,4 +706,222 @@ func Test_UpdateRepositoryPackages(t *testing.T) {
 	assert.Equal(t, renvLock.Packages["package18"].Version, "2.3.2")
 	assert.Equal(t, renvLock.Packages["package19"].Version, "5.2.2.4")
 	assert.Equal(t, renvLock.Packages["package21"].Version, "3.8.1")
+	// The hash of the previous version is not retained, and no hash is calculated for repository packages.
+	assert.Equal(t, renvLock.Packages["package15"].Hash, "")
+}
+
+func Test_FindPackageInRepositories(t *testing.T) {
+	packagesFiles := map[string]PackagesFile{
+		"Repo1": {
+			[]PackageDescription{
+				{
+					"package1", "1.2.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+		"Repo2": {
+			[]PackageDescription{
+				{
+					"package1", "1.5.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"package1", "1.4.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+		"Repo3": {
+			[]PackageDescription{
+				{
+					"package1", "1.5.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	repositoryNames := []string{"Repo1", "Repo2", "Repo3"}
+	p, repositoryName := FindPackageInRepositories("package1", ">=", "1.1.0", repositoryNames, packagesFiles)
+	assert.Equal(t, p.Version, "1.5.0")
+	assert.Equal(t, repositoryName, "Repo2")
+	p, repositoryName = FindPackageInRepositories("package1", "<", "1.5.0", repositoryNames, packagesFiles)
+	assert.Equal(t, p.Version, "1.4.0")
+	assert.Equal(t, repositoryName, "Repo2")
+	p, repositoryName = FindPackageInRepositories("package1", "", "", []string{"Repo3", "Repo2"}, packagesFiles)
+	assert.Equal(t, p.Version, "1.5.0")
+	assert.Equal(t, repositoryName, "Repo3")
+	_, repositoryName = FindPackageInRepositories("package1", ">=", "2.0.0", repositoryNames, packagesFiles)
+	assert.Equal(t, repositoryName, "")
+}
+
+func Test_CheckIfDependencyUpdateRequired(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package2": {
+				"package2", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package3": {
+				"package3", "1.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package3", "", "main", "aaa111", []string{}, "", nil,
+			},
+		},
+	}
+	assert.False(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{depends, "R", ">=", "4.0"}))
+	assert.False(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{suggests, "package4", "", ""}))
+	assert.True(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{imports, "package4", "", ""}))
+	assert.False(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{imports, "package2", ">=", "1.0"}))
+	assert.True(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{imports, "package2", ">=", "1.1"}))
+	assert.False(t, CheckIfDependencyUpdateRequired(renvLock, "package1", Dependency{linkingTo, "package3", ">", "1.0"}))
+}
+
+func Test_UpdateDependencies(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{{"Repo1", "https://repo1.example.com"}}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "2.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package2", "package3"}, "", nil,
+			},
+			"package2": {
+				"package2", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	}
+	packagesFiles := map[string]PackagesFile{
+		"Repo1": {
+			[]PackageDescription{
+				{
+					"package2", "1.5.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"package3", "3.0.0", "", "", []Dependency{{imports, "package4", "", ""}},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"package4", "4.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+				{
+					"package5", "5.0.0", "", "", []Dependency{},
+					"", "", "", "", "", "", "", []string{}, "", nil,
+				},
+			},
+			nil,
+		},
+	}
+	UpdateDependencies(&renvLock, map[string][]Dependency{
+		"package1": {
+			{depends, "R", ">=", "4.0"},
+			{imports, "package2", ">=", "1.2.0"},
+			{imports, "package3", "", ""},
+			{suggests, "package5", "", ""},
+		},
+	}, packagesFiles, []UpdateConstraint{{"package2", "patch"}})
+	// The update violating the update constraint is only reported.
+	assert.Equal(t, renvLock.Packages["package2"].Version, "1.5.0")
+	assert.Equal(t, renvLock.Packages["package3"].Version, "3.0.0")
+	assert.Equal(t, renvLock.Packages["package3"].Repository, "Repo1")
+	assert.Equal(t, renvLock.Packages["package3"].Requirements, []string{"package4"})
+	assert.Equal(t, renvLock.Packages["package4"].Version, "4.0.0")
+	_, ok := renvLock.Packages["package5"]
+	assert.False(t, ok)
+}
+
+func Test_PruneDependencies(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "2.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package2"}, "", nil,
+			},
+			"package2": {
+				"package2", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package3": {
+				"package3", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package4"}, "", nil,
+			},
+			"package4": {
+				"package4", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package5": {
+				"package5", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package2"}, "", nil,
+			},
+		},
+	}
+	dependencies := make(map[string][]string)
+	for k, p := range renvLock.Packages {
+		dependencies[k] = p.Requirements
+	}
+	PruneDependencies(&renvLock, map[string][]string{"package1": {"package2", "package3"}}, dependencies)
+	var packageNames []string
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	assert.Equal(t, packageNames, []string{"package1", "package2", "package5"})
+}
+
+func Test_PruneDependenciesLegacyRecords(t *testing.T) {
+	// package10 (written by an older renv version) has no Requirements, but it imports package3.
+	// The dependencies of other packages are read from the PACKAGES file.
+	content := []byte(`{
+  "R": {"Version": "4.3.2", "Repositories": [{"Name": "Repo1", "URL": "https://repo1.example.com"}]},
+  "Packages": {
+    "package1": {"Package": "package1", "Version": "1.0.0", "Source": "Repository", "Repository": "Repo1",
+      "Requirements": ["package3", "package8"]},
+    "package3": {"Package": "package3", "Version": "3.0.0", "Source": "Repository", "Repository": "Repo1"},
+    "package4": {"Package": "package4", "Version": "4.0.0", "Source": "Repository", "Repository": "Repo1"},
+    "package8": {"Package": "package8", "Version": "8.1.0", "Source": "Repository", "Repository": "Repo1"},
+    "package10": {"Package": "package10", "Version": "1.0.0", "Source": "Repository", "Repository": "Repo1",
+      "Imports": ["package3"]}
+  }
+}`)
+	readRenvLock := func() RenvLock {
+		var renvLock RenvLock
+		err := json.Unmarshal(content, &renvLock)
+		assert.Nil(t, err)
+		// package1 has been updated to a version without dependencies.
+		updatedPackage := renvLock.Packages["package1"]
+		updatedPackage.Version = "1.1.0"
+		updatedPackage.Requirements = []string{}
+		renvLock.Packages["package1"] = updatedPackage
+		return renvLock
+	}
+	updatedPackages := map[string][]Dependency{"package1": {}}
+	previousRequirements := map[string][]string{"package1": {"package3", "package8"}}
+	records := GetLockfileRecords(content)
+
+	renvLock := readRenvLock()
+	PruneDependencies(&renvLock, previousRequirements,
+		GetUpdatedPackageDependencies(renvLock, updatedPackages, records, mockedDownloadPruneFile))
+	var packageNames []string
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	// package3 is still imported by package10, and package3 imports package4.
+	assert.Equal(t, packageNames, []string{"package1", "package10", "package3", "package4"})
+
+	// The dependencies of package9 can't be determined, so no packages are removed.
+	renvLock = readRenvLock()
+	renvLock.Packages["package9"] = PackageDescription{
+		"package9", "9.0.0", "Repository", "Repo1", []Dependency{},
+		"", "", "", "", "", "", "", nil, "", nil,
+	}
+	PruneDependencies(&renvLock, previousRequirements,
+		GetUpdatedPackageDependencies(renvLock, updatedPackages, records, mockedDownloadPruneFile))
+	assert.Equal(t, len(renvLock.Packages), 6)
 }
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"html/template"
 	"os"
+	"sort"
 	"strings"
 )
 
@@ -34,6 +35,7 @@ type HTMLReport struct {
 	// is running with logLevel = warning or lower.
 	Warnings         string
 	Dependencies     []HTMLReportDependency
+	Overrides        []HTMLReportOverride
 	RenvLockContents string
 }
 
@@ -46,18 +48,26 @@ type HTMLReportDependency struct {
 	Name       string
 	Version    string
 	Repository string
+	License    string
 	Depends    string
 	Imports    string
 	LinkingTo  string
 	Suggests   string
 }
 
+type HTMLReportOverride struct {
+	Name       string
+	Version    string
+	Repository string
+	Remote     string
+}
+
 //go:embed template.html
 var htmlTemplate string
 
 func GenerateHTMLReport(outputPackageList []PackageDescription,
 	inputPackageDescriptions []PackageDescription, packagesFiles map[string]PackagesFile,
-	renvLockContents RenvLock, repositoryMap map[string]string) {
+	renvLockContents RenvLock, target Target, packageOverrides map[string]PackageOverride) {
 
 	var htmlReport HTMLReport
 
@@ -65,15 +75,31 @@ func GenerateHTMLReport(outputPackageList []PackageDescription,
 		HTMLReportConfigItem{"cfgFile", cfgFile},
 		HTMLReportConfigItem{"logLevel", logLevel},
 		HTMLReportConfigItem{"inputRenvLock", inputRenvLock},
-		HTMLReportConfigItem{"outputRenvLock", outputRenvLock},
+		HTMLReportConfigItem{"outputRenvLock", target.OutputRenvLock},
 		HTMLReportConfigItem{"allowIncompleteRenvLock", allowIncompleteRenvLock},
 		HTMLReportConfigItem{"updatePackages", updatePackages},
-		HTMLReportConfigItem{"reportFileName", reportFileName},
+		HTMLReportConfigItem{"reportFileName", target.ReportFileName},
+		HTMLReportConfigItem{"pin", strings.ReplaceAll(pinList, ",", ", ")},
+		HTMLReportConfigItem{"excludePackages", strings.ReplaceAll(excludePackages, ",", ", ")},
+		HTMLReportConfigItem{"recommendedPackages", recommendedPackagesMode},
+		HTMLReportConfigItem{"rVersion", target.RVersion},
+		HTMLReportConfigItem{"bioconductorVersion", target.BioconductorVersion},
+		HTMLReportConfigItem{"targetPlatform", target.TargetPlatform},
+		HTMLReportConfigItem{"snapshotDate", snapshotDate},
+		HTMLReportConfigItem{"lockfileVersion", lockfileVersion},
 		HTMLReportConfigItem{"inputPackageList", strings.ReplaceAll(inputPackageList, ",", ", ")},
 		HTMLReportConfigItem{"inputRepositoryList", strings.ReplaceAll(inputRepositoryList, ",", ", ")},
 		HTMLReportConfigItem{"inputPackages", strings.Join(inputPackages, ", ")},
 		HTMLReportConfigItem{"inputRepositories", strings.Join(inputRepositories, ", ")},
+		HTMLReportConfigItem{"allowedLicenses", strings.Join(licensePolicy.Allowed, ", ")},
+		HTMLReportConfigItem{"deniedLicenses", strings.Join(licensePolicy.Denied, ", ")},
 	)
+	if target.Name != "" {
+		htmlReport.Config = append(htmlReport.Config,
+			HTMLReportConfigItem{"target", target.Name},
+			HTMLReportConfigItem{"targetInputRepositories", strings.Join(target.InputRepositories, ", ")},
+		)
+	}
 
 	renvLockString, err := json.MarshalIndent(renvLockContents, "", "  ")
 	checkError(err)
@@ -92,9 +118,13 @@ func GenerateHTMLReport(outputPackageList []PackageDescription,
 		if p.Source == "Repository" {
 			// Get package dependencies from the PACKAGES file.
 			// Set the repository to repository alias.
-			repository = GetRepositoryKeyByValue(p.Repository, repositoryMap)
+			repository = GetRepositoryKeyByValue(p.Repository, target.RepositoryMap)
 			expectedPackageLocation = packagesFiles[p.Repository].Packages
 
+		} else if o, ok := packageOverrides[p.Package]; ok && o.GitPackage.Package != "" {
+			// Get package dependencies from DESCRIPTION file of package overridden by git repository.
+			repository = p.Source
+			expectedPackageLocation = []PackageDescription{o.GitPackage}
 		} else {
 			// Get package dependencies from DESCRIPTION files of input packages.
 			// Set the repository to GitLab or GitHub.
@@ -120,17 +150,30 @@ func GenerateHTMLReport(outputPackageList []PackageDescription,
 			}
 		}
 		htmlReport.Dependencies = append(htmlReport.Dependencies, HTMLReportDependency{
-			p.Package, p.Version, repository,
+			p.Package, p.Version, repository, p.Fields["License"],
 			strings.TrimSuffix(dependsList, ", "),
 			strings.TrimSuffix(importsList, ", "),
 			strings.TrimSuffix(linkingToList, ", "),
 			strings.TrimSuffix(suggestsList, ", "),
 		})
 	}
+	// Process the overrides in sorted order to generate predictable output.
+	var overriddenPackages []string
+	for k := range packageOverrides {
+		overriddenPackages = append(overriddenPackages, k)
+	}
+	sort.Strings(overriddenPackages)
+	for _, k := range overriddenPackages {
+		o := packageOverrides[k]
+		htmlReport.Overrides = append(htmlReport.Overrides, HTMLReportOverride{
+			o.Package, o.Version, o.Repository, o.Remote,
+		})
+	}
+
 	t, err := template.New("locksmithReport").Parse(htmlTemplate)
 	checkError(err)
 
-	reportFile, err := os.Create(reportFileName)
+	reportFile, err := os.Create(target.ReportFileName)
 	checkError(err)
 	defer reportFile.Close()
 
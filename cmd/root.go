@@ -40,6 +40,28 @@ var outputRenvLock string
 var allowIncompleteRenvLock string
 var updatePackages string
 var reportFileName string
+var pinList string
+var excludePackages string
+var recommendedPackagesMode string
+var rVersion string
+var bioconductorVersion string
+var targetPlatform string
+var lockfileVersion string
+var dryRun bool
+var pruneDependencies bool
+var gitUpdatePolicy string
+var gitTagConstraint string
+var snapshotDate string
+var pakLockfile string
+var sbomOutput string
+var dryRunOutput string
+
+// Package overrides, update constraints, targets and license policy can only be provided
+// in YAML configuration file.
+var packageOverrides []PackageOverride
+var updateConstraints []UpdateConstraint
+var targets []Target
+var licensePolicy LicensePolicy
 
 // In case the lists are provided as arrays in YAML configuration file:
 var inputPackages []string
@@ -109,7 +131,8 @@ func setLogLevel() {
 	log.AddHook(warnCaptureHook)
 	log.AddHook(errorCaptureHook)
 
-	fmt.Println(`logLevel = "` + logLevel + `"`)
+	// Printed to stderr, so that the output of subcommands such as diff can be redirected.
+	fmt.Fprintln(os.Stderr, `logLevel = "`+logLevel+`"`)
 	switch logLevel {
 	case "trace":
 		log.SetLevel(logrus.TraceLevel)
@@ -155,6 +178,25 @@ in an renv.lock-compatible file.`,
 			fmt.Println(`allowIncompleteRenvLock = "` + allowIncompleteRenvLock + `"`)
 			fmt.Println(`updatePackages = "` + updatePackages + `"`)
 			fmt.Println(`reportFileName = "` + reportFileName + `"`)
+			fmt.Println(`pin = "` + pinList + `"`)
+			fmt.Println(`excludePackages = "` + excludePackages + `"`)
+			fmt.Println(`recommendedPackages = "` + recommendedPackagesMode + `"`)
+			fmt.Println(`rVersion = "` + rVersion + `"`)
+			fmt.Println(`bioconductorVersion = "` + bioconductorVersion + `"`)
+			fmt.Println(`targetPlatform = "` + targetPlatform + `"`)
+			fmt.Println(`lockfileVersion = "` + lockfileVersion + `"`)
+			fmt.Println("dryRun =", dryRun)
+			fmt.Println("pruneDependencies =", pruneDependencies)
+			fmt.Println("gitUpdatePolicy =", gitUpdatePolicy)
+			fmt.Println("gitTagConstraint =", gitTagConstraint)
+			fmt.Println("snapshotDate =", snapshotDate)
+			fmt.Println("pakLockfile =", pakLockfile)
+			fmt.Println("sbomOutput =", sbomOutput)
+			fmt.Println(`dryRunOutput = "` + dryRunOutput + `"`)
+			fmt.Println("packageOverrides =", packageOverrides)
+			fmt.Println("updateConstraints =", updateConstraints)
+			fmt.Println("targets =", targets)
+			fmt.Println("licensePolicy =", licensePolicy)
 
 			if runtime.GOOS == "windows" {
 				localTempDirectory = os.Getenv("TMP") + `\tmp\locksmith`
@@ -162,21 +204,40 @@ in an renv.lock-compatible file.`,
 				localTempDirectory = "/tmp/locksmith"
 			}
 
+			if snapshotDate != "" {
+				CheckSnapshotDate(snapshotDate)
+			}
+
 			if inputRenvLock != "" {
-				renvLock := UpdateRenvLock(inputRenvLock, updatePackages)
-				writeJSON(outputRenvLock, renvLock)
+				renvLock := UpdateRenvLock(
+					inputRenvLock, updatePackages, targetPlatform, rVersion, pruneDependencies, gitUpdatePolicy,
+					gitTagConstraint, updateConstraints, snapshotDate,
+				)
+				if dryRun {
+					if ReportDryRun(inputRenvLock, renvLock, dryRunOutput) {
+						os.Exit(updatesAvailableExitCode)
+					}
+					return
+				}
+				writeJSON(outputRenvLock, GetUpdatedLockfileContents(inputRenvLock, renvLock, lockfileVersion))
 			} else {
+				if dryRun {
+					log.Warn("--dryRun is only supported when updating an existing lockfile with --inputRenvLock.")
+				}
 				packageDescriptionList, repositoryList, repositoryMap, allowedMissingDependencyTypes := ParseInput()
-				inputDescriptionFiles := DownloadDescriptionFiles(packageDescriptionList, DownloadTextFile)
+				// Each file is downloaded only once, even if it's required by multiple targets.
+				downloadFileFunction := CacheDownloads(DownloadTextFile)
+				inputDescriptionFiles := DownloadDescriptionFiles(packageDescriptionList, downloadFileFunction)
 				inputPackages := ParseDescriptionFileList(inputDescriptionFiles)
-				repositoryPackagesFiles := DownloadPackagesFiles(repositoryList, DownloadTextFile)
-				packagesFiles := ParsePackagesFiles(repositoryPackagesFiles)
-				outputPackageList := ConstructOutputPackageList(
-					inputPackages, packagesFiles, repositoryList, allowedMissingDependencyTypes,
-				)
-				renvLock := GenerateRenvLock(outputPackageList, repositoryMap)
-				GenerateHTMLReport(outputPackageList, inputPackages, packagesFiles, renvLock, repositoryMap)
-				writeJSON(outputRenvLock, renvLock)
+				var licenseViolations []string
+				commonWarnings, commonErrors := warnBuffer.String(), errorBuffer.String()
+				for _, target := range GetTargets(repositoryList, repositoryMap) {
+					ResetLogBuffers(commonWarnings, commonErrors)
+					licenseViolations = append(licenseViolations, GenerateTarget(
+						target, inputPackages, allowedMissingDependencyTypes, downloadFileFunction,
+					)...)
+				}
+				ReportLicenseViolations(licenseViolations)
 			}
 		},
 	}
@@ -207,9 +268,76 @@ in an renv.lock-compatible file.`,
 			`'package*,*abc,a*b,someOtherPackage'. By default all packages are updated.`)
 	rootCmd.PersistentFlags().StringVarP(&reportFileName, "reportFileName", "f", "locksmithReport.html",
 		"File name to save the output report.")
+	rootCmd.PersistentFlags().StringVarP(&pinList, "pin", "", "",
+		"Comma-separated list of overrides determining the version or the source of packages, "+
+			"taking precedence over the requirements in DESCRIPTION files. Each override follows the pattern: "+
+			"'<package><operator><version>@<repository>' where both the version constraint and the repository "+
+			"are optional, and instead of repository name a URL to DESCRIPTION file in a git repository can be given. "+
+			`Example: 'ggplot2==3.4.4,Matrix@CRAN,dplyr>=1.1.0,dplyr<2.0.0'.`)
+	rootCmd.PersistentFlags().StringVarP(&excludePackages, "excludePackages", "x", "",
+		"Expression with wildcards indicating which dependencies should not be resolved and should not be "+
+			"included in the output renv.lock. Follows the same pattern as --updatePackages. Example: "+
+			`'RDCOMClient,*Win*'. Dependencies excluded this way never cause locksmith to fail.`)
+	rootCmd.PersistentFlags().StringVarP(&recommendedPackagesMode, "recommendedPackages", "", "repository",
+		"Determines how R recommended packages (e.g. Matrix, MASS, lattice, survival, nlme) are processed: "+
+			"'repository' - downloaded from package repositories in the latest version, "+
+			"'installed' - assumed to be installed together with R in version --rVersion, "+
+			"'path' - downloaded from package repositories in the version bundled with R in version --rVersion.")
+	rootCmd.PersistentFlags().StringVarP(&rVersion, "rVersion", "", "",
+		"Version of the target R installation, e.g. '4.4.0' or '4.4'. It's saved in the output renv.lock.")
+	rootCmd.PersistentFlags().StringVarP(&bioconductorVersion, "bioconductorVersion", "", "",
+		"Bioconductor release saved in the output renv.lock, e.g. '3.18'. By default, it's derived from the URLs "+
+			"of Bioconductor repositories such as 'https://bioconductor.org/packages/3.18/bioc'.")
+	rootCmd.PersistentFlags().StringVarP(&targetPlatform, "targetPlatform", "", "",
+		"Platform on which the packages from the output renv.lock will be installed, in the format "+
+			"'<os>/<arch>' where <os> is one of: linux, windows, macos, and the optional <arch> is e.g. "+
+			"x86_64 or arm64. Packages which cannot be installed on that platform (according to OS_type, Archs "+
+			"and Built fields in PACKAGES files) are not taken into account.")
+	rootCmd.PersistentFlags().StringVarP(&lockfileVersion, "lockfileVersion", "", lockfileVersionLegacy,
+		"Format of the package records in the output renv.lock: 'legacy' - compact records containing "+
+			"only the fields describing the package source, 'full' - records containing all DESCRIPTION "+
+			"fields, as written by renv 1.0 and later.")
+
+	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dryRun", "", false,
+		"Perform the update of --inputRenvLock without saving the output renv.lock. Instead, print the list "+
+			"of changes and exit with code 3 if any packages would be updated.")
+	rootCmd.PersistentFlags().StringVarP(&dryRunOutput, "dryRunOutput", "", "",
+		"File name to save the list of changes found by --dryRun as JSON.")
+
+	rootCmd.PersistentFlags().BoolVarP(&pruneDependencies, "pruneDependencies", "", false,
+		"When updating --inputRenvLock, remove the packages which were required by the updated packages "+
+			"before the update, but are no longer required by any package in the lockfile. "+
+			"This may also remove packages used directly by the project, as they're not recorded in the lockfile.")
+
+	rootCmd.PersistentFlags().StringVarP(&gitUpdatePolicy, "gitUpdatePolicy", "", gitUpdatePolicyTrackRef,
+		"When updating --inputRenvLock, the policy used to update packages from git repositories: "+
+			"'track-ref' (follow the branch to which the package is locked, or the default branch if the package "+
+			"is locked to e.g. a tag), 'default-branch' or 'latest-tag' (the tag with the highest version).")
+
+	rootCmd.PersistentFlags().StringVarP(&gitTagConstraint, "gitTagConstraint", "", "",
+		"Comma-separated version constraints which the tags must satisfy when using "+
+			"--gitUpdatePolicy latest-tag, e.g. '>= 1.2.0, < 2.0.0'.")
+
+	rootCmd.PersistentFlags().StringVarP(&snapshotDate, "snapshotDate", "", "",
+		"Date (YYYY-MM-DD) as of which the packages from CRAN-like repositories should be resolved. "+
+			"CRAN and Posit Package Manager URLs are replaced with Posit Package Manager snapshot URLs.")
+
+	rootCmd.PersistentFlags().StringVarP(&pakLockfile, "pakLockfile", "", "",
+		"File name to save the lockfile in pak's pkg.lock format, in addition to renv.lock. "+
+			"For multiple targets, the target name is appended to the file name, e.g. pkg-linux.lock.")
+
+	rootCmd.PersistentFlags().StringVarP(&sbomOutput, "sbomOutput", "", "",
+		"Base file name to save the software bill of materials in CycloneDX (<sbomOutput>.cdx.json) "+
+			"and SPDX (<sbomOutput>.spdx.json) formats. For multiple targets, the target name is appended "+
+			"to the base file name.")
 
 	// Add version command.
 	rootCmd.AddCommand(extension.NewVersionCobraCmd())
+	rootCmd.AddCommand(newDiffCommand())
+	rootCmd.AddCommand(newVerifyCommand())
+	rootCmd.AddCommand(newLintCommand())
+	rootCmd.AddCommand(newMergeCommand())
+	rootCmd.AddCommand(newPruneCommand())
 
 	cfg := envy.CobraConfig{
 		Prefix:     "LOCKSMITH",
@@ -243,7 +371,7 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	} else {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 	}
 }
 
@@ -258,7 +386,10 @@ func initializeConfig() {
 	for _, v := range []string{
 		"logLevel", "inputPackageList", "inputRepositoryList", "gitHubToken", "gitLabToken",
 		"inputRenvLock", "outputRenvLock", "allowIncompleteRenvLock", "updatePackages",
-		"reportFileName",
+		"reportFileName", "pin", "excludePackages", "recommendedPackages", "rVersion",
+		"bioconductorVersion", "targetPlatform", "lockfileVersion", "dryRun", "dryRunOutput",
+		"pruneDependencies", "gitUpdatePolicy", "gitTagConstraint",
+		"snapshotDate", "pakLockfile", "sbomOutput",
 	} {
 		// If the flag has not been set in newRootCommand() and it has been set in initConfig().
 		// In other words: if it's not been provided in command line, but has been
@@ -273,4 +404,16 @@ func initializeConfig() {
 	// Check if a YAML list of input packages or input repositories has been provided in the configuration file.
 	inputPackages = viper.GetStringSlice("inputPackages")
 	inputRepositories = viper.GetStringSlice("inputRepositories")
+	// Check if package overrides have been provided in the configuration file.
+	err := viper.UnmarshalKey("packageOverrides", &packageOverrides)
+	checkError(err)
+	// Check if update constraints have been provided in the configuration file.
+	err = viper.UnmarshalKey("updateConstraints", &updateConstraints)
+	checkError(err)
+	// Check if multiple targets have been defined in the configuration file.
+	err = viper.UnmarshalKey("targets", &targets)
+	checkError(err)
+	// Check if license policy has been provided in the configuration file.
+	err = viper.UnmarshalKey("licensePolicy", &licensePolicy)
+	checkError(err)
 }
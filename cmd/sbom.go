@@ -0,0 +1,253 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const cycloneDXSpecVersion = "1.5"
+const spdxVersion = "SPDX-2.3"
+const spdxNoAssertion = "NOASSERTION"
+const spdxDocumentID = "SPDXRef-DOCUMENT"
+const spdxNamespacePrefix = "https://github.com/insightsengineering/locksmith/spdx/"
+
+// spdxLicenses maps the license names used in the License field of R packages (with whitespace removed)
+// to SPDX license identifiers.
+var spdxLicenses = map[string]string{
+	"MIT":                  "MIT",
+	"GPL":                  "GPL-2.0-or-later",
+	"GPL-2":                "GPL-2.0-only",
+	"GPL-3":                "GPL-3.0-only",
+	"GPL(>=2)":             "GPL-2.0-or-later",
+	"GPL(>=2.0)":           "GPL-2.0-or-later",
+	"GPL(>=3)":             "GPL-3.0-or-later",
+	"LGPL":                 "LGPL-2.0-or-later",
+	"LGPL-2":               "LGPL-2.0-only",
+	"LGPL-2.1":             "LGPL-2.1-only",
+	"LGPL-3":               "LGPL-3.0-only",
+	"LGPL(>=2)":            "LGPL-2.0-or-later",
+	"LGPL(>=2.1)":          "LGPL-2.1-or-later",
+	"LGPL(>=3)":            "LGPL-3.0-or-later",
+	"AGPL-3":               "AGPL-3.0-only",
+	"AGPL(>=3)":            "AGPL-3.0-or-later",
+	"ApacheLicense":        "Apache-2.0",
+	"ApacheLicense2.0":     "Apache-2.0",
+	"ApacheLicense(==2.0)": "Apache-2.0",
+	"ApacheLicense(>=2)":   "Apache-2.0",
+	"ApacheLicense(>=2.0)": "Apache-2.0",
+	"BSD_2_clause":         "BSD-2-Clause",
+	"BSD_3_clause":         "BSD-3-Clause",
+	"CC0":                  "CC0-1.0",
+	"CCBY4.0":              "CC-BY-4.0",
+	"CCBY-SA4.0":           "CC-BY-SA-4.0",
+	"MPL-2.0":              "MPL-2.0",
+	"Artistic-2.0":         "Artistic-2.0",
+	"EUPL":                 "EUPL-1.2",
+}
+
+// GetSPDXLicense converts the License field of an R package, e.g. 'GPL-2 | GPL-3' or 'MIT + file LICENSE',
+// into an SPDX license expression. It returns an empty string if any of the alternative licenses
+// can't be converted, e.g. 'file LICENSE'.
+func GetSPDXLicense(license string) string {
+	if license == "" {
+		return ""
+	}
+	var spdxLicenseList []string
+	for _, l := range strings.Split(license, "|") {
+		l = strings.Join(strings.Fields(l), "")
+		// The additional restrictions are not reflected in the SPDX expression.
+		l = strings.TrimSuffix(strings.TrimSuffix(l, "+fileLICENSE"), "+fileLICENCE")
+		spdxLicense, ok := spdxLicenses[l]
+		if !ok {
+			return ""
+		}
+		spdxLicenseList = append(spdxLicenseList, spdxLicense)
+	}
+	return strings.Join(spdxLicenseList, " OR ")
+}
+
+// GetPackagePurl returns the package URL (purl) identifying the package p, e.g. 'pkg:cran/dplyr@1.1.4'
+// or 'pkg:github/insightsengineering/teal@<sha>'. The repositoryURL is added as a percent-encoded qualifier
+// for packages from package repositories.
+func GetPackagePurl(p PackageDescription, repositoryURL string) string {
+	switch p.Source {
+	case GitHub:
+		purl := "pkg:github/" + strings.ToLower(p.RemoteUsername+"/"+p.RemoteRepo) + "@" + p.RemoteSha
+		if p.RemoteSubdir != "" {
+			purl += "#" + p.RemoteSubdir
+		}
+		return purl
+	case GitLab:
+		remoteHost := strings.TrimSuffix(GetGitRepositoryURL(p), "/"+p.RemoteUsername+"/"+p.RemoteRepo)
+		purl := "pkg:gitlab/" + p.RemoteUsername + "/" + p.RemoteRepo + "@" + p.RemoteSha +
+			"?" + url.Values{"repository_url": {remoteHost}}.Encode()
+		if p.RemoteSubdir != "" {
+			purl += "#" + p.RemoteSubdir
+		}
+		return purl
+	}
+	purlType := "cran"
+	if strings.Contains(repositoryURL, "bioconductor") {
+		purlType = "bioconductor"
+	}
+	purl := "pkg:" + purlType + "/" + p.Package + "@" + p.Version
+	if repositoryURL != "" {
+		purl += "?" + url.Values{"repository_url": {repositoryURL}}.Encode()
+	}
+	return purl
+}
+
+// GetPackageDownloadLocation returns the URL from which the package p can be downloaded.
+// For git packages, it's the repository URL pinned to the locked commit, in the format used by SPDX.
+func GetPackageDownloadLocation(p PackageDescription, repositoryURL string) string {
+	switch p.Source {
+	case GitHub, GitLab:
+		location := "git+" + GetGitRepositoryURL(p) + ".git@" + p.RemoteSha
+		if p.RemoteSubdir != "" {
+			location += "#" + p.RemoteSubdir
+		}
+		return location
+	}
+	if repositoryURL == "" {
+		return ""
+	}
+	return GetPackageDownloadURL(repositoryURL, p.Package, p.Version, "")
+}
+
+// getSortedSBOMPackages returns the packages from renvLock sorted by name, together with the map
+// from package names to purls, and the map from package names to repository URLs.
+func getSortedSBOMPackages(renvLock RenvLock) ([]PackageDescription, map[string]string, map[string]string) {
+	repositoryURLs := make(map[string]string)
+	for _, r := range renvLock.R.Repositories {
+		repositoryURLs[r.Name] = r.URL
+	}
+	var packageNames []string
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	var packages []PackageDescription
+	purls := make(map[string]string)
+	packageRepositoryURLs := make(map[string]string)
+	for _, k := range packageNames {
+		p := renvLock.Packages[k]
+		packages = append(packages, p)
+		packageRepositoryURLs[k] = repositoryURLs[p.Repository]
+		purls[k] = GetPackagePurl(p, packageRepositoryURLs[k])
+	}
+	return packages, purls, packageRepositoryURLs
+}
+
+// GenerateCycloneDX converts renvLock into an SBOM in CycloneDX format, created at timestamp.
+func GenerateCycloneDX(renvLock RenvLock, timestamp string) CycloneDXBom {
+	bom := CycloneDXBom{
+		"CycloneDX", cycloneDXSpecVersion, 1,
+		CycloneDXMetadata{
+			timestamp, CycloneDXTools{[]CycloneDXComponent{{Type: "application", Name: "locksmith"}}},
+		},
+		[]CycloneDXComponent{}, []CycloneDXDependency{},
+	}
+	packages, purls, repositoryURLs := getSortedSBOMPackages(renvLock)
+	for _, p := range packages {
+		component := CycloneDXComponent{
+			"library", purls[p.Package], p.Package, p.Version, purls[p.Package], nil, nil, nil,
+		}
+		if license := p.Fields["License"]; license != "" {
+			spdxLicense := GetSPDXLicense(license)
+			if spdxLicense != "" && !strings.Contains(spdxLicense, " OR ") {
+				component.Licenses = []CycloneDXLicenseChoice{{CycloneDXLicense{spdxLicense, ""}}}
+			} else {
+				component.Licenses = []CycloneDXLicenseChoice{{CycloneDXLicense{"", license}}}
+			}
+		}
+		switch p.Source {
+		case GitHub, GitLab:
+			component.ExternalReferences = []CycloneDXExternalReference{{"vcs", GetGitRepositoryURL(p)}}
+			component.Properties = []CycloneDXProperty{
+				{"locksmith:remoteRef", p.RemoteRef}, {"locksmith:remoteSha", p.RemoteSha},
+			}
+		default:
+			if repositoryURLs[p.Package] != "" {
+				component.ExternalReferences = []CycloneDXExternalReference{
+					{"distribution", GetPackageDownloadLocation(p, repositoryURLs[p.Package])},
+				}
+				component.Properties = []CycloneDXProperty{{"locksmith:repository", repositoryURLs[p.Package]}}
+			}
+		}
+		bom.Components = append(bom.Components, component)
+		dependency := CycloneDXDependency{purls[p.Package], []string{}}
+		for _, r := range p.Requirements {
+			if purl, ok := purls[r]; ok {
+				dependency.DependsOn = append(dependency.DependsOn, purl)
+			}
+		}
+		bom.Dependencies = append(bom.Dependencies, dependency)
+	}
+	return bom
+}
+
+// GenerateSPDX converts renvLock into an SBOM in SPDX format, named documentName and created at timestamp.
+func GenerateSPDX(renvLock RenvLock, documentName string, timestamp string) SPDXDocument {
+	document := SPDXDocument{
+		spdxVersion, "CC0-1.0", spdxDocumentID, documentName,
+		spdxNamespacePrefix + documentName + "-" + timestamp,
+		SPDXCreationInfo{timestamp, []string{"Tool: locksmith"}},
+		[]SPDXPackage{}, []SPDXRelationship{},
+	}
+	packages, purls, repositoryURLs := getSortedSBOMPackages(renvLock)
+	for _, p := range packages {
+		spdxPackage := SPDXPackage{
+			p.Package, "SPDXRef-Package-" + p.Package, p.Version,
+			GetPackageDownloadLocation(p, repositoryURLs[p.Package]), false, spdxNoAssertion,
+			GetSPDXLicense(p.Fields["License"]), "",
+			[]SPDXExternalRef{{"PACKAGE-MANAGER", "purl", purls[p.Package]}},
+		}
+		if spdxPackage.DownloadLocation == "" {
+			spdxPackage.DownloadLocation = spdxNoAssertion
+		}
+		if spdxPackage.LicenseDeclared == "" {
+			spdxPackage.LicenseDeclared = spdxNoAssertion
+			spdxPackage.LicenseComments = p.Fields["License"]
+		}
+		document.Packages = append(document.Packages, spdxPackage)
+		document.Relationships = append(
+			document.Relationships, SPDXRelationship{spdxDocumentID, "DESCRIBES", spdxPackage.SPDXID},
+		)
+	}
+	for _, p := range packages {
+		for _, r := range p.Requirements {
+			if _, ok := renvLock.Packages[r]; ok {
+				document.Relationships = append(document.Relationships, SPDXRelationship{
+					"SPDXRef-Package-" + p.Package, "DEPENDS_ON", "SPDXRef-Package-" + r,
+				})
+			}
+		}
+	}
+	return document
+}
+
+// GetSBOMFileNames returns the names of CycloneDX and SPDX files for the target, based on the configured
+// sbomOutput, e.g. 'sbom-linux.cdx.json' and 'sbom-linux.spdx.json' for 'sbom' and target named 'linux'.
+func GetSBOMFileNames(sbomOutput string, targetName string) (string, string) {
+	if targetName != "" {
+		sbomOutput += "-" + targetName
+	}
+	return sbomOutput + ".cdx.json", sbomOutput + ".spdx.json"
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getSBOMTestRenvLock() RenvLock {
+	return RenvLock{
+		RenvLockContents{"4.3.2", []RenvLockRepository{{"CRAN", "https://cran.example.com"}}}, nil,
+		map[string]PackageDescription{
+			"package1": {
+				"package1", "1.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{"package2", "package3"}, "",
+				map[string]string{"License": "MIT + file LICENSE"},
+			},
+			"package2": {
+				"package2", "2.0.0", "Repository", "CRAN", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", map[string]string{"License": "file LICENSE"},
+			},
+			"package3": {
+				"package3", "3.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "Org1", "Repo3", "pkg", "main", "aaa111", []string{"package4"}, "",
+				map[string]string{"License": "GPL-2 | GPL-3"},
+			},
+		},
+	}
+}
+
+func Test_GetSPDXLicense(t *testing.T) {
+	assert.Equal(t, GetSPDXLicense("MIT + file LICENSE"), "MIT")
+	assert.Equal(t, GetSPDXLicense("GPL (>= 2)"), "GPL-2.0-or-later")
+	assert.Equal(t, GetSPDXLicense("GPL-2 | GPL-3"), "GPL-2.0-only OR GPL-3.0-only")
+	assert.Equal(t, GetSPDXLicense("Apache License (== 2.0)"), "Apache-2.0")
+	assert.Equal(t, GetSPDXLicense("BSD_3_clause + file LICENSE"), "BSD-3-Clause")
+	assert.Equal(t, GetSPDXLicense("file LICENSE"), "")
+	assert.Equal(t, GetSPDXLicense("MIT | file LICENSE"), "")
+	assert.Equal(t, GetSPDXLicense(""), "")
+}
+
+func Test_GetPackagePurl(t *testing.T) {
+	renvLock := getSBOMTestRenvLock()
+	assert.Equal(t, GetPackagePurl(renvLock.Packages["package1"], "https://cran.example.com"),
+		"pkg:cran/package1@1.0.0?repository_url=https%3A%2F%2Fcran.example.com")
+	assert.Equal(t, GetPackagePurl(renvLock.Packages["package1"], "https://bioconductor.org/packages/3.18/bioc"),
+		"pkg:bioconductor/package1@1.0.0?repository_url=https%3A%2F%2Fbioconductor.org%2Fpackages%2F3.18%2Fbioc")
+	// Characters such as ':' and '/' in the repository URL are percent-encoded.
+	assert.Equal(t, GetPackagePurl(renvLock.Packages["package1"], "https://repo.example.com:8443/cran/latest"),
+		"pkg:cran/package1@1.0.0?repository_url=https%3A%2F%2Frepo.example.com%3A8443%2Fcran%2Flatest")
+	assert.Equal(t, GetPackagePurl(renvLock.Packages["package3"], ""), "pkg:github/org1/repo3@aaa111#pkg")
+	assert.Equal(t, GetPackagePurl(PackageDescription{
+		"package4", "4.0.0", "GitLab", "", []Dependency{},
+		"gitlab", "gitlab.example.com", "group1", "package4", "", "main", "bbb222", []string{}, "", nil,
+	}, ""), "pkg:gitlab/group1/package4@bbb222?repository_url=https%3A%2F%2Fgitlab.example.com")
+}
+
+func Test_GetSBOMFileNames(t *testing.T) {
+	cycloneDXFileName, spdxFileName := GetSBOMFileNames("sbom", "")
+	assert.Equal(t, cycloneDXFileName, "sbom.cdx.json")
+	assert.Equal(t, spdxFileName, "sbom.spdx.json")
+	cycloneDXFileName, spdxFileName = GetSBOMFileNames("sbom", "linux")
+	assert.Equal(t, cycloneDXFileName, "sbom-linux.cdx.json")
+	assert.Equal(t, spdxFileName, "sbom-linux.spdx.json")
+}
+
+func Test_GenerateCycloneDX(t *testing.T) {
+	bom := GenerateCycloneDX(getSBOMTestRenvLock(), "2024-01-01T00:00:00Z")
+	assert.Equal(t, bom.BomFormat, "CycloneDX")
+	assert.Equal(t, bom.Metadata.Timestamp, "2024-01-01T00:00:00Z")
+	assert.Equal(t, len(bom.Components), 3)
+	assert.Equal(t, bom.Components[0].Licenses, []CycloneDXLicenseChoice{{CycloneDXLicense{"MIT", ""}}})
+	assert.Equal(t, bom.Components[0].ExternalReferences, []CycloneDXExternalReference{
+		{"distribution", "https://cran.example.com/src/contrib/package1_1.0.0.tar.gz"},
+	})
+	assert.Equal(t, bom.Components[1].Licenses, []CycloneDXLicenseChoice{{CycloneDXLicense{"", "file LICENSE"}}})
+	assert.Equal(t, bom.Components[2].Licenses, []CycloneDXLicenseChoice{{CycloneDXLicense{"", "GPL-2 | GPL-3"}}})
+	assert.Equal(t, bom.Components[2].ExternalReferences, []CycloneDXExternalReference{
+		{"vcs", "https://github.com/Org1/Repo3"},
+	})
+	assert.Equal(t, bom.Components[2].Properties, []CycloneDXProperty{
+		{"locksmith:remoteRef", "main"}, {"locksmith:remoteSha", "aaa111"},
+	})
+	assert.Equal(t, bom.Dependencies, []CycloneDXDependency{
+		{"pkg:cran/package1@1.0.0?repository_url=https%3A%2F%2Fcran.example.com", []string{
+			"pkg:cran/package2@2.0.0?repository_url=https%3A%2F%2Fcran.example.com", "pkg:github/org1/repo3@aaa111#pkg",
+		}},
+		{"pkg:cran/package2@2.0.0?repository_url=https%3A%2F%2Fcran.example.com", []string{}},
+		// package4 is not present in the lockfile.
+		{"pkg:github/org1/repo3@aaa111#pkg", []string{}},
+	})
+}
+
+func Test_GenerateSPDX(t *testing.T) {
+	document := GenerateSPDX(getSBOMTestRenvLock(), "renv", "2024-01-01T00:00:00Z")
+	assert.Equal(t, document.DocumentNamespace,
+		"https://github.com/insightsengineering/locksmith/spdx/renv-2024-01-01T00:00:00Z")
+	assert.Equal(t, document.Packages, []SPDXPackage{
+		{
+			"package1", "SPDXRef-Package-package1", "1.0.0",
+			"https://cran.example.com/src/contrib/package1_1.0.0.tar.gz", false, "NOASSERTION", "MIT", "",
+			[]SPDXExternalRef{{"PACKAGE-MANAGER", "purl",
+				"pkg:cran/package1@1.0.0?repository_url=https%3A%2F%2Fcran.example.com"}},
+		},
+		{
+			"package2", "SPDXRef-Package-package2", "2.0.0",
+			"https://cran.example.com/src/contrib/package2_2.0.0.tar.gz", false, "NOASSERTION", "NOASSERTION",
+			"file LICENSE", []SPDXExternalRef{{"PACKAGE-MANAGER", "purl",
+				"pkg:cran/package2@2.0.0?repository_url=https%3A%2F%2Fcran.example.com"}},
+		},
+		{
+			"package3", "SPDXRef-Package-package3", "3.0.0", "git+https://github.com/Org1/Repo3.git@aaa111#pkg",
+			false, "NOASSERTION", "GPL-2.0-only OR GPL-3.0-only", "",
+			[]SPDXExternalRef{{"PACKAGE-MANAGER", "purl", "pkg:github/org1/repo3@aaa111#pkg"}},
+		},
+	})
+	assert.Equal(t, document.Relationships, []SPDXRelationship{
+		{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Package-package1"},
+		{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Package-package2"},
+		{"SPDXRef-DOCUMENT", "DESCRIBES", "SPDXRef-Package-package3"},
+		{"SPDXRef-Package-package1", "DEPENDS_ON", "SPDXRef-Package-package2"},
+		{"SPDXRef-Package-package1", "DEPENDS_ON", "SPDXRef-Package-package3"},
+	})
+}
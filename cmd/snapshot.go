@@ -0,0 +1,72 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Posit Package Manager URL used instead of CRAN mirrors when --snapshotDate is set.
+const packageManagerCRANURL = "https://packagemanager.posit.co/cran/"
+
+// Hosts of CRAN mirrors which are replaced by Posit Package Manager snapshots.
+var cranMirrorHosts = []string{"cloud.r-project.org", "cran.r-project.org", "cran.rstudio.com", "cran.rstudio.org"}
+
+// Posit Package Manager CRAN repositories, including the ones serving binary packages for Linux, e.g.
+// 'https://packagemanager.posit.co/cran/latest' or 'https://p3m.dev/cran/__linux__/jammy/2024-06-01'.
+var packageManagerURLRegexp = regexp.MustCompile(
+	`^(https?://[^/]+/(?:[^/]+/)?cran/(?:__linux__/[^/]+/)?)(latest|\d{4}-\d{2}-\d{2})/?$`,
+)
+
+// CheckSnapshotDate exits with an error if snapshotDate is not in the YYYY-MM-DD format.
+func CheckSnapshotDate(snapshotDate string) {
+	if _, err := time.Parse("2006-01-02", snapshotDate); err != nil {
+		log.Fatal("Incorrect format of --snapshotDate: ", snapshotDate, ". Please use YYYY-MM-DD, e.g. 2024-06-01.")
+	}
+}
+
+// GetSnapshotRepositoryURL returns the URL of the snapshot of the CRAN-like repository located at
+// repositoryURL, as of snapshotDate. Posit Package Manager URLs get the date in place of 'latest'
+// (or of another date), while CRAN mirrors are replaced by Posit Package Manager snapshot URLs.
+// Other repositories (e.g. BioConductor) and all repositories in case snapshotDate is empty are left unchanged.
+func GetSnapshotRepositoryURL(repositoryURL string, snapshotDate string) string {
+	if snapshotDate == "" {
+		return repositoryURL
+	}
+	if match := packageManagerURLRegexp.FindStringSubmatch(repositoryURL); match != nil {
+		return match[1] + snapshotDate
+	}
+	host := strings.Split(strings.TrimPrefix(strings.TrimPrefix(repositoryURL, "http://"), https), "/")[0]
+	if stringInSlice(host, cranMirrorHosts) {
+		return packageManagerCRANURL + snapshotDate
+	}
+	return repositoryURL
+}
+
+// ApplySnapshotDate replaces the URLs of CRAN-like repositories in renvLock header with the URLs
+// of their snapshots as of snapshotDate.
+func ApplySnapshotDate(renvLock *RenvLock, snapshotDate string) {
+	for i, r := range renvLock.R.Repositories {
+		snapshotURL := GetSnapshotRepositoryURL(r.URL, snapshotDate)
+		if snapshotURL != r.URL {
+			log.Info("Using ", snapshotURL, " instead of ", r.URL, " for ", r.Name, " repository.")
+			renvLock.R.Repositories[i].URL = snapshotURL
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetSnapshotRepositoryURL(t *testing.T) {
+	assert.Equal(t, GetSnapshotRepositoryURL("https://cloud.r-project.org", "2024-06-01"),
+		"https://packagemanager.posit.co/cran/2024-06-01")
+	assert.Equal(t, GetSnapshotRepositoryURL("https://cran.rstudio.com/", "2024-06-01"),
+		"https://packagemanager.posit.co/cran/2024-06-01")
+	assert.Equal(t, GetSnapshotRepositoryURL("https://packagemanager.posit.co/cran/latest", "2024-06-01"),
+		"https://packagemanager.posit.co/cran/2024-06-01")
+	assert.Equal(t, GetSnapshotRepositoryURL("https://p3m.dev/cran/__linux__/jammy/2023-01-15", "2024-06-01"),
+		"https://p3m.dev/cran/__linux__/jammy/2024-06-01")
+	assert.Equal(t, GetSnapshotRepositoryURL("https://bioconductor.org/packages/3.18/bioc", "2024-06-01"),
+		"https://bioconductor.org/packages/3.18/bioc")
+	assert.Equal(t, GetSnapshotRepositoryURL("https://cloud.r-project.org", ""), "https://cloud.r-project.org")
+}
+
+func Test_ParseRepositoryListWithSnapshotDate(t *testing.T) {
+	snapshotDate = "2024-06-01"
+	defer func() { snapshotDate = "" }()
+	repositoryList, repositoryMap := ParseRepositoryList([]string{
+		"CRAN=https://cloud.r-project.org", "BioC=https://bioconductor.org/packages/3.18/bioc",
+	})
+	assert.Equal(t, repositoryList, []string{
+		"https://packagemanager.posit.co/cran/2024-06-01", "https://bioconductor.org/packages/3.18/bioc",
+	})
+	assert.Equal(t, repositoryMap["CRAN"], "https://packagemanager.posit.co/cran/2024-06-01")
+}
+
+func Test_ApplySnapshotDate(t *testing.T) {
+	renvLock := ReadRenvLock("testdata/renv_lossless.lock")
+	ApplySnapshotDate(&renvLock, "2024-06-01")
+	assert.Equal(t, renvLock.R.Repositories, []RenvLockRepository{
+		{"CRAN", "https://packagemanager.posit.co/cran/2024-06-01"},
+	})
+	// The snapshot URLs are saved in the header of the updated lockfile.
+	outputJSON, err := json.Marshal(
+		GetUpdatedLockfileContents("testdata/renv_lossless.lock", renvLock, lockfileVersionLegacy),
+	)
+	assert.Nil(t, err)
+	var outputRenvLock RenvLock
+	err = json.Unmarshal(outputJSON, &outputRenvLock)
+	assert.Nil(t, err)
+	assert.Equal(t, outputRenvLock.R, RenvLockContents{
+		"4.3.2", []RenvLockRepository{{"CRAN", "https://packagemanager.posit.co/cran/2024-06-01"}},
+	})
+}
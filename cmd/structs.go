@@ -50,11 +50,34 @@ type DescriptionFile struct {
 
 type PackagesFile struct {
 	Packages []PackageDescription `json:"packages"`
+	// PathPackages contains the packages located in the subdirectories of the repository,
+	// mapped by the value of the 'Path' field, e.g. '4.4.0/Recommended'.
+	// Such subdirectories contain e.g. the recommended packages bundled with the given R version.
+	PathPackages map[string][]PackageDescription `json:"pathPackages"`
 }
 
 type RenvLock struct {
-	R        RenvLockContents              `json:"R"`
-	Packages map[string]PackageDescription `json:"Packages"`
+	R            RenvLockContents              `json:"R"`
+	Bioconductor *RenvLockBioconductor         `json:"Bioconductor,omitempty"`
+	Packages     map[string]PackageDescription `json:"Packages"`
+}
+
+// FullRenvLock represents the renv.lock in which the package records contain all DESCRIPTION fields,
+// as written by renv 1.0 and later.
+type FullRenvLock struct {
+	R            RenvLockContents          `json:"R"`
+	Bioconductor *RenvLockBioconductor     `json:"Bioconductor,omitempty"`
+	Packages     map[string]LockfileRecord `json:"Packages"`
+}
+
+// LockfileRecord represents a package record in the full renv.lock format. It is stored as a list
+// in order to preserve the order of the fields in the output JSON.
+type LockfileRecord []LockfileRecordField
+
+type LockfileRecordField struct {
+	Name string
+	// Value can be either a string or a list of strings.
+	Value interface{}
 }
 
 type RenvLockRepository struct {
@@ -63,9 +86,17 @@ type RenvLockRepository struct {
 }
 
 type RenvLockContents struct {
+	// Version stores the version of R for which the lockfile has been generated.
+	Version      string               `json:"Version,omitempty"`
 	Repositories []RenvLockRepository `json:"Repositories"`
 }
 
+// RenvLockBioconductor represents the Bioconductor section of renv.lock, which is used by renv
+// to select the Bioconductor repositories.
+type RenvLockBioconductor struct {
+	Version string `json:"Version"`
+}
+
 // PackageDescription represents an R package.
 type PackageDescription struct {
 	// Package stores the package name.
@@ -89,10 +120,13 @@ type PackageDescription struct {
 	RemoteSubdir   string `json:"RemoteSubdir,omitempty"`
 	RemoteRef      string `json:"RemoteRef,omitempty"`
 	RemoteSha      string `json:"RemoteSha,omitempty"`
-	// Requirements field is not used, except to be able to unmarshal renv.lock JSON in which
-	// this field is present.
+	// Requirements stores the names of hard dependencies (Depends, Imports, LinkingTo)
+	// of the package, excluding base packages.
 	Requirements []string `json:"Requirements,omitempty"`
 	Hash         string   `json:"Hash,omitempty"`
+	// Fields stores all fields from the PACKAGES file entry or the DESCRIPTION file.
+	// They are used e.g. to calculate the package hash, and to generate full lockfile records.
+	Fields map[string]string `json:"-"`
 }
 
 type Dependency struct {
@@ -106,3 +140,250 @@ type DependencyVersion struct {
 	VersionOperator string `json:"operator"`
 	VersionValue    string `json:"value"`
 }
+
+// RequiredVersion represents a version constraint on a package, together with the package which requires it.
+type RequiredVersion struct {
+	DependencyVersion
+	// RequiredBy describes the package record with the constraint, e.g. 'package1 (renv.lock)'.
+	RequiredBy string
+}
+
+// PackageOverride represents a user-defined rule which takes precedence over the requirements
+// found in DESCRIPTION files and over the default order of package repositories, when resolving
+// a given package.
+type PackageOverride struct {
+	// Package stores the name of the overridden package.
+	Package string `json:"package"`
+	// Version stores comma-separated version constraints, for example '== 3.4.4'
+	// or '>= 1.2.0, < 2.0.0'. A version without an operator is treated as an exact version.
+	Version string `json:"version"`
+	// Repository stores the name (alias) of the package repository from which the package
+	// should be downloaded.
+	Repository string `json:"repository"`
+	// Remote stores the URL to the raw DESCRIPTION file in a git repository (in the same format
+	// as input packages) from which the package should be downloaded instead of a package repository.
+	Remote string `json:"remote"`
+	// VersionConstraints contains the parsed Version field.
+	VersionConstraints []DependencyVersion `json:"-"`
+	// RepositoryURL contains the URL of the package repository referenced by the Repository field.
+	RepositoryURL string `json:"-"`
+	// GitPackage contains the package description retrieved from the Remote DESCRIPTION file.
+	GitPackage PackageDescription `json:"-"`
+	// ArchivedRepositoryURLs contains the URLs of package repositories whose archive contains
+	// the exact version pinned by the override, lower than the version listed in PACKAGES file.
+	ArchivedRepositoryURLs []string `json:"-"`
+}
+
+// UpdateConstraint represents a user-defined rule limiting the version changes allowed
+// when updating the packages from package repositories in an existing renv.lock.
+type UpdateConstraint struct {
+	// Package stores the package name, or a comma-separated list of wildcard expressions
+	// in the same format as --updatePackages, e.g. 'dplyr' or 'tidy*,dplyr'.
+	Package string `json:"package"`
+	// Allow can be one of: 'patch' (only the last version component may change),
+	// 'minor' (no major version changes) or 'major' (any version changes).
+	Allow string `json:"allow"`
+}
+
+// Target represents a single output renv.lock, generated for the same set of input packages
+// but from a different set of package repositories, e.g. for a different platform.
+type Target struct {
+	// Name identifies the target in the logs and in the HTML report.
+	Name string `json:"name"`
+	// InputRepositories contains the list of package repositories in the format 'Repo1=URL1',
+	// sorted according to their priorities (descending).
+	InputRepositories []string `json:"inputRepositories"`
+	// RVersion is the version of the target R installation.
+	RVersion string `json:"rVersion"`
+	// BioconductorVersion is the Bioconductor release saved in the lockfile.
+	BioconductorVersion string `json:"bioconductorVersion"`
+	// TargetPlatform is the platform in the format '<os>/<arch>'.
+	TargetPlatform string `json:"targetPlatform"`
+	// OutputRenvLock is the file name to save the output renv.lock file.
+	OutputRenvLock string `json:"outputRenvLock"`
+	// ReportFileName is the file name to save the output report.
+	ReportFileName string `json:"reportFileName"`
+	// RepositoryList contains the URLs of InputRepositories.
+	RepositoryList []string `json:"-"`
+	// RepositoryMap is a map from the package repository alias (name) to the package repository URL.
+	RepositoryMap map[string]string `json:"-"`
+}
+
+// LockfileDiff represents the differences between two renv.lock files.
+type LockfileDiff struct {
+	Added      []PackageChange `json:"added"`
+	Removed    []PackageChange `json:"removed"`
+	Upgraded   []PackageChange `json:"upgraded"`
+	Downgraded []PackageChange `json:"downgraded"`
+	// SourceChanged contains the packages for which the source, the repository or the git reference has changed.
+	SourceChanged []PackageChange `json:"sourceChanged"`
+	ShaChanged    []PackageChange `json:"shaChanged"`
+}
+
+// PackageChange represents the change of a single package between two renv.lock files.
+// The Old* fields are empty for added packages, and the New* fields are empty for removed packages.
+type PackageChange struct {
+	Package    string `json:"package"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+	OldSource  string `json:"oldSource,omitempty"`
+	NewSource  string `json:"newSource,omitempty"`
+	OldSha     string `json:"oldSha,omitempty"`
+	NewSha     string `json:"newSha,omitempty"`
+}
+
+// PakLock represents the pkg.lock lockfile used by pak (pkgdepends).
+type PakLock struct {
+	LockfileVersion int              `json:"lockfile_version"`
+	OS              string           `json:"os"`
+	RVersion        string           `json:"r_version"`
+	Platform        string           `json:"platform"`
+	Packages        []PakLockPackage `json:"packages"`
+}
+
+// PakMetadataField represents a single field of the metadata of a package in pak's pkg.lock.
+type PakMetadataField struct {
+	Name  string
+	Value string
+}
+
+// PakLockPackage represents a single package in pak's pkg.lock.
+type PakLockPackage struct {
+	// Ref is the pak package reference, e.g. 'dplyr' or 'insightsengineering/teal@<sha>'.
+	Ref     string `json:"ref"`
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Type is one of: 'standard' (packages from package repositories), 'github' or 'gitlab'.
+	Type         string   `json:"type"`
+	Direct       bool     `json:"direct"`
+	Binary       bool     `json:"binary"`
+	Dependencies []string `json:"dependencies"`
+	Vignettes    bool     `json:"vignettes"`
+	// NeedsCompilation is true if the package must be compiled when installed from source.
+	NeedsCompilation bool              `json:"needscompilation"`
+	Metadata         map[string]string `json:"metadata"`
+	// Sources contains the URLs from which the package can be downloaded.
+	Sources []string `json:"sources"`
+	// Target is the relative path to which the package file is downloaded.
+	Target      string   `json:"target"`
+	Platform    string   `json:"platform"`
+	RVersion    string   `json:"rversion"`
+	DirectPkg   bool     `json:"directpkg"`
+	License     string   `json:"license"`
+	DepTypes    []string `json:"dep_types"`
+	Params      []string `json:"params"`
+	InstallArgs string   `json:"install_args"`
+	RepoType    string   `json:"repotype,omitempty"`
+}
+
+// CycloneDXBom represents a software bill of materials in CycloneDX JSON format.
+type CycloneDXBom struct {
+	BomFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     CycloneDXMetadata     `json:"metadata"`
+	Components   []CycloneDXComponent  `json:"components"`
+	Dependencies []CycloneDXDependency `json:"dependencies"`
+}
+
+type CycloneDXMetadata struct {
+	Timestamp string         `json:"timestamp"`
+	Tools     CycloneDXTools `json:"tools"`
+}
+
+type CycloneDXTools struct {
+	Components []CycloneDXComponent `json:"components"`
+}
+
+type CycloneDXComponent struct {
+	Type string `json:"type"`
+	// BomRef is the identifier of the component in the dependency graph, equal to the package purl.
+	BomRef             string                       `json:"bom-ref,omitempty"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	Purl               string                       `json:"purl,omitempty"`
+	Licenses           []CycloneDXLicenseChoice     `json:"licenses,omitempty"`
+	ExternalReferences []CycloneDXExternalReference `json:"externalReferences,omitempty"`
+	Properties         []CycloneDXProperty          `json:"properties,omitempty"`
+}
+
+type CycloneDXLicenseChoice struct {
+	License CycloneDXLicense `json:"license"`
+}
+
+// CycloneDXLicense contains either the SPDX license identifier or the license name
+// (e.g. 'GPL (>= 2)') if it couldn't be converted to an SPDX identifier.
+type CycloneDXLicense struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type CycloneDXExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type CycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// SPDXDocument represents a software bill of materials in SPDX JSON format.
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships"`
+}
+
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type SPDXPackage struct {
+	Name             string `json:"name"`
+	SPDXID           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	// LicenseDeclared contains the SPDX license expression, or 'NOASSERTION' if the license
+	// couldn't be converted to an SPDX expression. In such case, the original License field
+	// is saved in LicenseComments.
+	LicenseDeclared string            `json:"licenseDeclared"`
+	LicenseComments string            `json:"licenseComments,omitempty"`
+	ExternalRefs    []SPDXExternalRef `json:"externalRefs"`
+}
+
+type SPDXExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// LicensePolicy represents the user-defined rules specifying which package licenses are acceptable.
+// The entries can be license names as written in the License field of R packages (e.g. 'GPL-3' or
+// 'file LICENSE'), SPDX license identifiers (e.g. 'GPL-3.0-only'), or wildcard expressions (e.g. 'AGPL*').
+type LicensePolicy struct {
+	// Allowed contains the accepted licenses. If it's not empty, all packages must be available
+	// under at least one of the allowed licenses.
+	Allowed []string `json:"allowed"`
+	// Denied contains the licenses which are not accepted, even if they match the Allowed list.
+	Denied []string `json:"denied"`
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strings"
+	"time"
+)
+
+// GetTargets returns the list of targets for which the renv.lock files should be generated.
+// If no targets have been defined in the YAML configuration file, a single target is returned,
+// based on the CLI flags and the package repositories in repositoryList and repositoryMap.
+// Otherwise, the targets from the configuration file are returned, with unset fields
+// defaulting to the values of the respective CLI flags.
+func GetTargets(repositoryList []string, repositoryMap map[string]string) []Target {
+	if len(targets) == 0 {
+		return []Target{{
+			"", []string{}, rVersion, bioconductorVersion, targetPlatform, outputRenvLock, reportFileName,
+			repositoryList, repositoryMap,
+		}}
+	}
+	var outputTargets []Target
+	targetNames := make(map[string]bool)
+	outputFileNames := make(map[string]bool)
+	for _, t := range targets {
+		if t.Name == "" {
+			log.Fatal("Name missing in targets entry.")
+		}
+		if targetNames[t.Name] {
+			log.Fatal("Target ", t.Name, " has been defined more than once.")
+		}
+		targetNames[t.Name] = true
+		if len(t.InputRepositories) == 0 {
+			log.Fatal("No package repositories specified for target ", t.Name, ".")
+		}
+		t.RepositoryList, t.RepositoryMap = ParseRepositoryList(t.InputRepositories)
+		if t.RVersion == "" {
+			t.RVersion = rVersion
+		}
+		if t.BioconductorVersion == "" {
+			t.BioconductorVersion = bioconductorVersion
+		}
+		if t.TargetPlatform == "" {
+			t.TargetPlatform = targetPlatform
+		}
+		if t.OutputRenvLock == "" {
+			t.OutputRenvLock = "renv-" + t.Name + ".lock"
+		}
+		if t.ReportFileName == "" {
+			t.ReportFileName = "locksmithReport-" + t.Name + ".html"
+		}
+		for _, fileName := range []string{t.OutputRenvLock, t.ReportFileName} {
+			if outputFileNames[fileName] {
+				log.Fatal("Output file ", fileName, " of target ", t.Name, " is used by another target.")
+			}
+			outputFileNames[fileName] = true
+		}
+		log.Debug("Target ", t.Name, ": repositories = ", t.RepositoryList, ", rVersion = ", t.RVersion,
+			", targetPlatform = ", t.TargetPlatform, ", outputRenvLock = ", t.OutputRenvLock)
+		outputTargets = append(outputTargets, t)
+	}
+	return outputTargets
+}
+
+// ResetLogBuffers replaces the contents of the buffers with warnings and errors shown in the HTML report
+// with commonWarnings and commonErrors, i.e. the messages logged before generating any target,
+// so that the report of each target contains only the messages relevant to that target.
+func ResetLogBuffers(commonWarnings string, commonErrors string) {
+	warnBuffer.Reset()
+	warnBuffer.WriteString(commonWarnings)
+	errorBuffer.Reset()
+	errorBuffer.WriteString(commonErrors)
+}
+
+// GenerateTarget resolves the dependencies of inputPackages using the package repositories defined
+// for the target, and saves the resulting renv.lock and HTML report to the files defined for the target.
+// It returns the list of packages violating the license policy.
+func GenerateTarget(target Target, inputPackages []PackageDescription, allowedMissingDependencyTypes []string,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) []string {
+	if target.Name != "" {
+		log.Info("Generating ", target.OutputRenvLock, " for target ", target.Name, ".")
+	}
+	overrides := ParsePackageOverrides(packageOverrides, pinList, target.RepositoryMap)
+	DownloadPackageOverrideRemotes(overrides, downloadFileFunction)
+	repositoryPackagesFiles := DownloadPackagesFiles(target.RepositoryList, downloadFileFunction)
+	packagesFiles := ParsePackagesFiles(repositoryPackagesFiles)
+	FilterPackagesFiles(packagesFiles, target.TargetPlatform, target.RVersion)
+	FindArchivedPackageOverrides(overrides, packagesFiles, target.RepositoryList, downloadFileFunction)
+	installedPackages := ProcessRecommendedPackages(
+		recommendedPackagesMode, target.RVersion, packagesFiles, target.RepositoryList,
+	)
+	outputPackageList := ConstructOutputPackageList(
+		inputPackages, packagesFiles, target.RepositoryList, allowedMissingDependencyTypes, overrides,
+		excludePackages, installedPackages,
+	)
+	renvLock := GenerateRenvLock(
+		outputPackageList, target.RepositoryMap, target.RVersion,
+		GetBioconductorVersion(target.BioconductorVersion, target.RepositoryList),
+	)
+	GenerateHTMLReport(outputPackageList, inputPackages, packagesFiles, renvLock, target, overrides)
+	writeJSON(target.OutputRenvLock, GetLockfileContents(renvLock, lockfileVersion))
+	if pakLockfile != "" {
+		writeJSON(
+			GetPakLockfileName(pakLockfile, target.Name),
+			GeneratePakLock(renvLock, inputPackages, target.TargetPlatform, target.RVersion),
+		)
+	}
+	if sbomOutput != "" {
+		cycloneDXFileName, spdxFileName := GetSBOMFileNames(sbomOutput, target.Name)
+		timestamp := time.Now().UTC().Format(time.RFC3339)
+		writeJSON(cycloneDXFileName, GenerateCycloneDX(renvLock, timestamp))
+		writeJSON(
+			spdxFileName, GenerateSPDX(renvLock, strings.TrimSuffix(target.OutputRenvLock, ".lock"), timestamp),
+		)
+	}
+	violations := CheckLicensePolicy(renvLock, inputPackages, licensePolicy)
+	if target.Name != "" {
+		for i := range violations {
+			violations[i] = target.Name + ": " + violations[i]
+		}
+	}
+	return violations
+}
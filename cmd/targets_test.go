@@ -0,0 +1,88 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetTargets(t *testing.T) {
+	previousRVersion, previousTargetPlatform := rVersion, targetPlatform
+	previousOutputRenvLock, previousReportFileName := outputRenvLock, reportFileName
+	defer func() {
+		rVersion, targetPlatform = previousRVersion, previousTargetPlatform
+		outputRenvLock, reportFileName = previousOutputRenvLock, previousReportFileName
+	}()
+	rVersion = "4.4"
+	targetPlatform = "linux"
+	outputRenvLock = "renv.lock"
+	reportFileName = "locksmithReport.html"
+	targets = []Target{}
+	defaultTargets := GetTargets(
+		[]string{"https://repo1.example.com/repo1"},
+		map[string]string{"Repo1": "https://repo1.example.com/repo1"},
+	)
+	assert.Equal(t, defaultTargets, []Target{{
+		"", []string{}, "4.4", "", "linux", "renv.lock", "locksmithReport.html",
+		[]string{"https://repo1.example.com/repo1"},
+		map[string]string{"Repo1": "https://repo1.example.com/repo1"},
+	}})
+
+	targets = []Target{
+		{Name: "linux", InputRepositories: []string{"Repo1=https://repo1.example.com/repo1"}},
+		{
+			Name: "windows",
+			InputRepositories: []string{
+				"Repo2=https://repo2.example.com/bin/windows/contrib/4.3",
+				"Repo1=https://repo1.example.com/repo1",
+			},
+			RVersion: "4.3", TargetPlatform: "windows", OutputRenvLock: "windows.lock",
+		},
+	}
+	defer func() { targets = []Target{} }()
+	configTargets := GetTargets([]string{}, map[string]string{})
+	assert.Equal(t, configTargets, []Target{
+		{
+			"linux", []string{"Repo1=https://repo1.example.com/repo1"}, "4.4", "", "linux",
+			"renv-linux.lock", "locksmithReport-linux.html",
+			[]string{"https://repo1.example.com/repo1"},
+			map[string]string{"Repo1": "https://repo1.example.com/repo1"},
+		},
+		{
+			"windows", []string{
+				"Repo2=https://repo2.example.com/bin/windows/contrib/4.3",
+				"Repo1=https://repo1.example.com/repo1",
+			}, "4.3", "", "windows", "windows.lock", "locksmithReport-windows.html",
+			[]string{"https://repo2.example.com/bin/windows/contrib/4.3", "https://repo1.example.com/repo1"},
+			map[string]string{
+				"Repo1": "https://repo1.example.com/repo1",
+				"Repo2": "https://repo2.example.com/bin/windows/contrib/4.3",
+			},
+		},
+	})
+}
+
+func Test_ResetLogBuffers(t *testing.T) {
+	defer ResetLogBuffers("", "")
+	warnBuffer.WriteString("warning from target 1\n")
+	errorBuffer.WriteString("error from target 1\n")
+	ResetLogBuffers("common warning\n", "common error\n")
+	assert.Equal(t, warnBuffer.String(), "common warning\n")
+	assert.Equal(t, errorBuffer.String(), "common error\n")
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"strconv"
@@ -55,10 +56,10 @@ func ParseInput() ([]string, []string, map[string]string, []string) {
 			"or supply the list under inputPackages in YAML config.",
 		)
 	}
-	if len(inputRepositoryList) < 1 && len(inputRepositories) == 0 {
+	if len(inputRepositoryList) < 1 && len(inputRepositories) == 0 && len(targets) == 0 {
 		log.Fatal(
 			"No package repositories specified. Please use the --inputRepositoryList flag ",
-			"or supply the list under inputRepositories in YAML config.",
+			"or supply the list under inputRepositories or targets in YAML config.",
 		)
 	}
 	var packageList []string
@@ -88,6 +89,18 @@ func ParseInput() ([]string, []string, map[string]string, []string) {
 		log.Debug("allowedMissingDependencyTypes = ", allowedMissingDependencyTypes)
 	}
 
+	outputRepositoryList, outputRepositoryMap := ParseRepositoryList(repositoryList)
+	log.Debug("inputPackageList = ", packageList)
+	log.Debug("inputRepositoryList = ", outputRepositoryList)
+	log.Debug("inputRepositoryMap = ", outputRepositoryMap)
+	return packageList, outputRepositoryList, outputRepositoryMap, allowedMissingDependencyTypes
+}
+
+// ParseRepositoryList processes the list of package repositories in the format 'Repo1=URL1'.
+// If --snapshotDate is set, the URLs of CRAN-like repositories are replaced with the URLs of their snapshots.
+// It returns the list of package repository URLs (in the same order as in repositoryList),
+// and a map from package repository alias (name) to the package repository URL.
+func ParseRepositoryList(repositoryList []string) ([]string, map[string]string) {
 	outputRepositoryMap := make(map[string]string)
 	var outputRepositoryList []string
 	for _, r := range repositoryList {
@@ -95,13 +108,14 @@ func ParseInput() ([]string, []string, map[string]string, []string) {
 			log.Fatal("Incorrect format of package repositories. Please try: 'Repo1=URL1,Repo2=URL2,...'")
 		}
 		repository := strings.Split(r, "=")
-		outputRepositoryMap[repository[0]] = repository[1]
-		outputRepositoryList = append(outputRepositoryList, repository[1])
+		repositoryURL := GetSnapshotRepositoryURL(repository[1], snapshotDate)
+		if repositoryURL != repository[1] {
+			log.Info("Using ", repositoryURL, " instead of ", repository[1], " for ", repository[0], " repository.")
+		}
+		outputRepositoryMap[repository[0]] = repositoryURL
+		outputRepositoryList = append(outputRepositoryList, repositoryURL)
 	}
-	log.Debug("inputPackageList = ", packageList)
-	log.Debug("inputRepositoryList = ", outputRepositoryList)
-	log.Debug("inputRepositoryMap = ", outputRepositoryMap)
-	return packageList, outputRepositoryList, outputRepositoryMap, allowedMissingDependencyTypes
+	return outputRepositoryList, outputRepositoryMap
 }
 
 func stringsToInts(input []string) []int {
@@ -115,9 +129,13 @@ func stringsToInts(input []string) []int {
 }
 
 func writeJSON(filename string, j interface{}) {
-	s, err := json.MarshalIndent(j, "", "  ")
+	var s bytes.Buffer
+	encoder := json.NewEncoder(&s)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(j)
 	checkError(err)
 
-	err = os.WriteFile(filename, s, 0644) //#nosec
+	err = os.WriteFile(filename, s.Bytes(), 0644) //#nosec
 	checkError(err)
 }
@@ -0,0 +1,254 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit code used by verify and lint subcommands when problems have been found.
+const problemsFoundExitCode = 1
+
+func newVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify <renv.lock>",
+		Short: "Verify that all packages in renv.lock can be downloaded",
+		Long: `Verify that all packages in renv.lock can be downloaded: the package versions
+from package repositories are looked up in the PACKAGES files and in the Archive,
+and their download URLs are checked, while for packages from git repositories,
+the DESCRIPTION file is downloaded from the locked commit.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			setLogLevel()
+			problems := VerifyRenvLock(ReadRenvLock(args[0]), CacheDownloads(DownloadTextFile), CheckURLExists)
+			ReportProblems(problems, args[0])
+		},
+	}
+}
+
+// ReportProblems prints the list of problems found in the lockfile fileName,
+// and exits with a non-zero exit code if there are any.
+func ReportProblems(problems []string, fileName string) {
+	if len(problems) == 0 {
+		fmt.Println("No problems found in " + fileName + ".")
+		return
+	}
+	fmt.Println("Problems found in " + fileName + ":")
+	for _, p := range problems {
+		fmt.Println("  " + p)
+	}
+	os.Exit(problemsFoundExitCode)
+}
+
+// GetPackageDownloadURL returns the URL of the package file in the given version in the package
+// repository located at repositoryURL. path is the value of the Path field from the PACKAGES file, if any.
+func GetPackageDownloadURL(repositoryURL string, packageName string, version string, path string) string {
+	switch {
+	case strings.Contains(repositoryURL, "/bin/windows/"):
+		return repositoryURL + "/" + packageName + "_" + version + ".zip"
+	case strings.Contains(repositoryURL, "/bin/macosx"):
+		return repositoryURL + "/" + packageName + "_" + version + ".tgz"
+	}
+	if path != "" {
+		path += "/"
+	}
+	return repositoryURL + "/src/contrib/" + path + packageName + "_" + version + ".tar.gz"
+}
+
+// FindPackageDownloadURL searches for the package version in the PACKAGES file of the repository
+// located at repositoryURL, and then in the repository Archive. It returns the URL from which the package
+// can be downloaded, or an empty string if the package version could not be found.
+func FindPackageDownloadURL(p PackageDescription, repositoryURL string, packagesFile PackagesFile,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) string {
+	for _, packageDescription := range packagesFile.Packages {
+		if packageDescription.Package == p.Package && packageDescription.Version == p.Version {
+			return GetPackageDownloadURL(repositoryURL, p.Package, p.Version, "")
+		}
+	}
+	for path, pathPackages := range packagesFile.PathPackages {
+		for _, packageDescription := range pathPackages {
+			if packageDescription.Package == p.Package && packageDescription.Version == p.Version {
+				return GetPackageDownloadURL(repositoryURL, p.Package, p.Version, path)
+			}
+		}
+	}
+	if strings.Contains(repositoryURL, "/bin/") {
+		// Archives are available only in source package repositories.
+		return ""
+	}
+	if stringInSlice(p.Version, GetArchivedPackageVersions(repositoryURL, p.Package, downloadFileFunction)) {
+		return repositoryURL + "/src/contrib/Archive/" + p.Package + "/" + p.Package + "_" + p.Version + ".tar.gz"
+	}
+	return ""
+}
+
+// VerifyGitPackage checks whether the DESCRIPTION file of the git package p can be downloaded
+// from the locked commit, and whether it contains the locked version. It returns the problem
+// description, or an empty string if there are no problems.
+func VerifyGitPackage(p PackageDescription,
+	downloadFileFunction func(string, map[string]string) (int64, string, error)) string {
+	if p.RemoteSha == "" {
+		return "no RemoteSha"
+	}
+	descriptionURL, token := GetDescriptionFileURL(p, p.RemoteSha)
+	_, descriptionContent, err := downloadFileFunction(descriptionURL, token)
+	if err != nil {
+		return "commit " + p.RemoteSha + " not found in " + GetGitRepositoryURL(p) +
+			" (could not download " + descriptionURL + ": " + err.Error() + ")"
+	}
+	if version := ParseDCF(descriptionContent)["Version"]; version != p.Version {
+		return "commit " + p.RemoteSha + " contains version " + version + " instead of " + p.Version
+	}
+	return ""
+}
+
+// Bioconductor repositories used by renv for packages with the Bioconductor source.
+var bioconductorRepositories = []string{"bioc", "data/annotation", "data/experiment", "workflows"}
+
+// GetBioconductorRepositories returns the map from the names to the URLs of the Bioconductor
+// repositories for the given Bioconductor release.
+func GetBioconductorRepositories(bioconductorVersion string) map[string]string {
+	repositories := make(map[string]string)
+	for _, r := range bioconductorRepositories {
+		repositories["BioC "+r] = "https://bioconductor.org/packages/" + bioconductorVersion + "/" + r
+	}
+	return repositories
+}
+
+// GetSearchedRepositories returns the names of the repositories in which the package p (named packageName)
+// should be searched for: the Bioconductor repositories for packages with the Bioconductor source, the repository
+// referenced by the package, or all repositories defined in the lockfile (repositoryNames) if the referenced
+// repository has not been defined. It returns the problem description if the repositories can't be determined,
+// and nil if the package should be skipped.
+func GetSearchedRepositories(packageName string, p PackageDescription, renvLock RenvLock, repositoryNames []string,
+	bioconductorRepositoryNames []string) ([]string, string) {
+	var searchedRepositories []string
+	switch {
+	case p.Source == "Bioconductor":
+		if renvLock.Bioconductor == nil {
+			return nil, "Bioconductor version has not been defined in the lockfile"
+		}
+		searchedRepositories = bioconductorRepositoryNames
+	case p.Source != "Repository":
+		log.Warn("Skipping package ", packageName, " with source ", p.Source, ".")
+		return nil, ""
+	case stringInSlice(p.Repository, repositoryNames):
+		searchedRepositories = []string{p.Repository}
+	default:
+		// Same as when updating the lockfile, a package from an undefined repository
+		// is searched for in all repositories defined in the lockfile.
+		searchedRepositories = repositoryNames
+		log.Warn("Repository ", p.Repository, " referenced by package ", packageName,
+			" has not been defined in the lockfile.")
+	}
+	if len(searchedRepositories) == 0 {
+		return nil, "repository " + p.Repository + " has not been defined in the lockfile"
+	}
+	return searchedRepositories, ""
+}
+
+// VerifyRepositoryPackage checks whether the package p can be found in any of searchedRepositories,
+// and whether its download URL is available. It returns the problem description, or an empty string
+// if there are no problems.
+func VerifyRepositoryPackage(p PackageDescription, searchedRepositories []string,
+	repositoryURLs map[string]string, packagesFiles map[string]PackagesFile,
+	downloadFileFunction func(string, map[string]string) (int64, string, error),
+	checkURLFunction func(string) error) string {
+	var downloadURL string
+	for _, repositoryName := range searchedRepositories {
+		downloadURL = FindPackageDownloadURL(
+			p, repositoryURLs[repositoryName], packagesFiles[repositoryName], downloadFileFunction,
+		)
+		if downloadURL != "" {
+			break
+		}
+	}
+	if downloadURL == "" {
+		return "version " + p.Version + " not found in PACKAGES or Archive of " + strings.Join(searchedRepositories, ", ")
+	}
+	if err := checkURLFunction(downloadURL); err != nil {
+		return downloadURL + " is not available: " + err.Error()
+	}
+	return ""
+}
+
+// VerifyRenvLock checks whether all packages in renvLock can be downloaded. Packages from package
+// repositories are searched for in the PACKAGES files and in the Archive of the repositories defined
+// in the lockfile header (downloaded with downloadFileFunction), and their download URLs are checked
+// with checkURLFunction. Packages with the Bioconductor source are searched for in the repositories
+// of the Bioconductor release defined in the lockfile. Packages from git repositories are checked with
+// VerifyGitPackage. Packages with other sources (e.g. Local or URL) are skipped.
+// It returns the list of problems sorted by package name.
+func VerifyRenvLock(renvLock RenvLock,
+	downloadFileFunction func(string, map[string]string) (int64, string, error),
+	checkURLFunction func(string) error) []string {
+	repositoryURLs := make(map[string]string)
+	var repositoryNames []string
+	packagesFiles := make(map[string]PackagesFile)
+	for _, r := range renvLock.R.Repositories {
+		repositoryURLs[r.Name] = r.URL
+		repositoryNames = append(repositoryNames, r.Name)
+		packagesFiles[r.Name] = ProcessPackagesFile(GetPackagesFileContent(r.URL, downloadFileFunction))
+	}
+	var bioconductorRepositoryNames []string
+	for _, p := range renvLock.Packages {
+		if p.Source != "Bioconductor" || renvLock.Bioconductor == nil {
+			continue
+		}
+		// Bioconductor repositories are only downloaded if there are any Bioconductor packages.
+		for name, url := range GetBioconductorRepositories(renvLock.Bioconductor.Version) {
+			repositoryURLs[name] = url
+			bioconductorRepositoryNames = append(bioconductorRepositoryNames, name)
+			packagesFiles[name] = ProcessPackagesFile(GetPackagesFileContent(url, downloadFileFunction))
+		}
+		sort.Strings(bioconductorRepositoryNames)
+		break
+	}
+	var packageNames []string
+	for k := range renvLock.Packages {
+		packageNames = append(packageNames, k)
+	}
+	sort.Strings(packageNames)
+	problems := []string{}
+	for _, k := range packageNames {
+		p := renvLock.Packages[k]
+		log.Debug("Verifying package ", k, " version ", p.Version, ".")
+		if p.Source == GitHub || p.Source == GitLab {
+			if problem := VerifyGitPackage(p, downloadFileFunction); problem != "" {
+				problems = append(problems, k+": "+problem)
+			}
+			continue
+		}
+		searchedRepositories, problem := GetSearchedRepositories(
+			k, p, renvLock, repositoryNames, bioconductorRepositoryNames,
+		)
+		if problem == "" && searchedRepositories != nil {
+			problem = VerifyRepositoryPackage(
+				p, searchedRepositories, repositoryURLs, packagesFiles, downloadFileFunction, checkURLFunction,
+			)
+		}
+		if problem != "" {
+			problems = append(problems, k+": "+problem)
+		}
+	}
+	return problems
+}
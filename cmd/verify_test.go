@@ -0,0 +1,178 @@
+/*
+Copyright 2023 F. Hoffmann-La Roche AG
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockedDownloadVerifiedFile(url string, _ map[string]string) (int64, string, error) {
+	switch url {
+	case "https://repo1.example.com/src/contrib/PACKAGES":
+		return 0, "Package: package1\nVersion: 1.0.0\n\nPackage: package2\nVersion: 2.1.0\n\n" +
+			"Package: package5\nVersion: 5.0.0\nPath: 4.4.0/Recommended\n", nil
+	case "https://repo1.example.com/src/contrib/Archive/package2/":
+		return 0, `<a href="package2_2.0.0.tar.gz">package2_2.0.0.tar.gz</a>`, nil
+	case "https://bioconductor.org/packages/3.18/bioc/src/contrib/PACKAGES":
+		return 0, "Package: package7\nVersion: 7.0.0\n", nil
+	case "https://raw.githubusercontent.com/org1/package3/aaa111/DESCRIPTION":
+		return 0, "Package: package3\nVersion: 3.0.0\n", nil
+	}
+	return 0, "", errors.New("Received status code 404")
+}
+
+func mockedCheckURLExists(url string) error {
+	switch url {
+	case "https://repo1.example.com/src/contrib/package1_1.0.0.tar.gz",
+		"https://repo1.example.com/src/contrib/Archive/package2/package2_2.0.0.tar.gz",
+		"https://bioconductor.org/packages/3.18/bioc/src/contrib/package7_7.0.0.tar.gz":
+		return nil
+	}
+	return errors.New("Received status code 404")
+}
+
+func Test_GetPackageDownloadURL(t *testing.T) {
+	assert.Equal(t, GetPackageDownloadURL("https://repo1.example.com", "package1", "1.0.0", ""),
+		"https://repo1.example.com/src/contrib/package1_1.0.0.tar.gz")
+	assert.Equal(t, GetPackageDownloadURL("https://repo1.example.com", "package1", "1.0.0", "4.4.0/Recommended"),
+		"https://repo1.example.com/src/contrib/4.4.0/Recommended/package1_1.0.0.tar.gz")
+	assert.Equal(t, GetPackageDownloadURL("https://repo1.example.com/bin/windows/contrib/4.3", "package1", "1.0.0", ""),
+		"https://repo1.example.com/bin/windows/contrib/4.3/package1_1.0.0.zip")
+	assert.Equal(t, GetPackageDownloadURL("https://repo1.example.com/bin/macosx/contrib/4.3", "package1", "1.0.0", ""),
+		"https://repo1.example.com/bin/macosx/contrib/4.3/package1_1.0.0.tgz")
+}
+
+func Test_VerifyRenvLock(t *testing.T) {
+	renvLock := RenvLock{
+		RenvLockContents{"", []RenvLockRepository{{"Repo1", "https://repo1.example.com"}}},
+		&RenvLockBioconductor{"3.18"},
+		map[string]PackageDescription{
+			// Available in PACKAGES.
+			"package1": {
+				"package1", "1.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			// Available in Archive.
+			"package2": {
+				"package2", "2.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package3": {
+				"package3", "3.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package3", "", "main", "aaa111", []string{}, "", nil,
+			},
+			"package4": {
+				"package4", "4.0.0", "GitHub", "", []Dependency{},
+				"github", "api.github.com", "org1", "package4", "", "main", "bbb222", []string{}, "", nil,
+			},
+			// Available in PACKAGES, but the file can't be downloaded.
+			"package5": {
+				"package5", "5.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package6": {
+				"package6", "6.0.0", "Repository", "Repo1", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			// Available in PACKAGES of the Bioconductor repository.
+			"package7": {
+				"package7", "7.0.0", "Bioconductor", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package8": {
+				"package8", "8.0.0", "Bioconductor", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			// Skipped.
+			"package9": {
+				"package9", "9.0.0", "Local", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+			"package10": {
+				"package10", "10.0.0", "URL", "", []Dependency{},
+				"", "", "", "", "", "", "", []string{}, "", nil,
+			},
+		},
+	}
+	assert.Equal(t, VerifyRenvLock(renvLock, mockedDownloadVerifiedFile, mockedCheckURLExists), []string{
+		"package4: commit bbb222 not found in https://github.com/org1/package4 (could not download " +
+			"https://raw.githubusercontent.com/org1/package4/bbb222/DESCRIPTION: Received status code 404)",
+		"package5: https://repo1.example.com/src/contrib/4.4.0/Recommended/package5_5.0.0.tar.gz " +
+			"is not available: Received status code 404",
+		"package6: version 6.0.0 not found in PACKAGES or Archive of Repo1",
+		"package8: version 8.0.0 not found in PACKAGES or Archive of BioC bioc, BioC data/annotation, " +
+			"BioC data/experiment, BioC workflows",
+	})
+	// Bioconductor packages can't be verified without the Bioconductor version.
+	renvLock.Bioconductor = nil
+	delete(renvLock.Packages, "package8")
+	assert.Equal(t, VerifyRenvLock(renvLock, mockedDownloadVerifiedFile, mockedCheckURLExists), []string{
+		"package4: commit bbb222 not found in https://github.com/org1/package4 (could not download " +
+			"https://raw.githubusercontent.com/org1/package4/bbb222/DESCRIPTION: Received status code 404)",
+		"package5: https://repo1.example.com/src/contrib/4.4.0/Recommended/package5_5.0.0.tar.gz " +
+			"is not available: Received status code 404",
+		"package6: version 6.0.0 not found in PACKAGES or Archive of Repo1",
+		"package7: Bioconductor version has not been defined in the lockfile",
+	})
+}
+
+func Test_GetSearchedRepositories(t *testing.T) {
+	renvLock := RenvLock{RenvLockContents{"4.3.2", []RenvLockRepository{}}, nil, nil}
+	repositoryNames := []string{"CRAN", "Repo1"}
+	bioconductorRepositoryNames := []string{"BioC bioc", "BioC workflows"}
+	p := PackageDescription{
+		"package1", "1.0.0", "Repository", "Repo1", []Dependency{},
+		"", "", "", "", "", "", "", []string{}, "", nil,
+	}
+	searchedRepositories, problem := GetSearchedRepositories(
+		"package1", p, renvLock, repositoryNames, bioconductorRepositoryNames,
+	)
+	assert.Equal(t, searchedRepositories, []string{"Repo1"})
+	assert.Equal(t, problem, "")
+	p.Repository = "Repo2"
+	searchedRepositories, problem = GetSearchedRepositories(
+		"package1", p, renvLock, repositoryNames, bioconductorRepositoryNames,
+	)
+	assert.Equal(t, searchedRepositories, []string{"CRAN", "Repo1"})
+	assert.Equal(t, problem, "")
+	searchedRepositories, problem = GetSearchedRepositories(
+		"package1", p, renvLock, []string{}, bioconductorRepositoryNames,
+	)
+	assert.Nil(t, searchedRepositories)
+	assert.Equal(t, problem, "repository Repo2 has not been defined in the lockfile")
+	p.Source = "Bioconductor"
+	searchedRepositories, problem = GetSearchedRepositories(
+		"package1", p, renvLock, repositoryNames, bioconductorRepositoryNames,
+	)
+	assert.Nil(t, searchedRepositories)
+	assert.Equal(t, problem, "Bioconductor version has not been defined in the lockfile")
+	renvLock.Bioconductor = &RenvLockBioconductor{"3.18"}
+	searchedRepositories, problem = GetSearchedRepositories(
+		"package1", p, renvLock, repositoryNames, bioconductorRepositoryNames,
+	)
+	assert.Equal(t, searchedRepositories, bioconductorRepositoryNames)
+	assert.Equal(t, problem, "")
+	p.Source = "Local"
+	searchedRepositories, problem = GetSearchedRepositories(
+		"package1", p, renvLock, repositoryNames, bioconductorRepositoryNames,
+	)
+	assert.Nil(t, searchedRepositories)
+	assert.Equal(t, problem, "")
+}